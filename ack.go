@@ -0,0 +1,280 @@
+package gubgub
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AckSubscriber is a func that processes a Delivery and returns true if it should continue
+// processing more messages. Unlike a Subscriber, a message is only considered processed once the
+// delivery is acknowledged with Ack. Messages that are neither acknowledged nor rejected within the
+// visibility timeout of the topic are delivered again.
+type AckSubscriber[T any] func(d *Delivery[T]) bool
+
+// AckSubscribable is implemented by topics that support at-least-once delivery with
+// acknowledgments. Closing such a topic waits for every delivered message to be acknowledged or
+// rejected. Unsubscribing doesn't wait: messages that were not acknowledged yet are handed to the
+// WithOnUndelivered callback instead.
+type AckSubscribable[T any] interface {
+	SubscribeAck(AckSubscriber[T]) (Subscription, error)
+}
+
+// Delivery is a message handed to an AckSubscriber. Exactly one of Ack or Nack must be called once
+// the message is processed. Ack and Nack are thread safe so the message may be processed in a
+// different go routine.
+type Delivery[T any] struct {
+	// Message is the delivered message.
+	Message T
+
+	// Attempt is 1 for the first delivery of the message and goes up each time it is delivered
+	// again.
+	Attempt int
+
+	pending    *pendingAck[T]
+	subscriber *ackSubscriber[T]
+}
+
+// Ack acknowledges that the message was processed so that it is not delivered again. Settling a
+// message that was already acknowledged or rejected does nothing.
+func (d *Delivery[T]) Ack() {
+	d.subscriber.settle(d.pending)
+}
+
+// Nack rejects the message. If requeue is true the message is delivered again right away, otherwise
+// it is discarded and reported to the dead letter topic with a RejectedError. Rejecting a delivery that
+// was superseded by a newer attempt does nothing.
+func (d *Delivery[T]) Nack(requeue bool) {
+	if requeue {
+		d.subscriber.redeliver(d.pending, d.Attempt)
+		return
+	}
+
+	if d.subscriber.settle(d.pending) {
+		d.subscriber.options.TriggerDeadLetter(d.Message, &RejectedError{attempts: d.Attempt}, nil)
+	}
+}
+
+// pendingAck tracks a message waiting to be acknowledged.
+type pendingAck[T any] struct {
+	msg       T
+	attempts  int
+	timer     *time.Timer // redelivers the message once the visibility timeout expires
+	scheduled bool        // true if the message is about to be delivered again
+	settled   bool
+}
+
+// ackSubscriber is the ClosableSubscriber behind SubscribeAck. It keeps track of the messages that
+// were delivered but not acknowledged yet and delivers them again when needed. The AckSubscriber is
+// never called concurrently, not even to deliver a message again.
+type ackSubscriber[T any] struct {
+	fn      AckSubscriber[T]
+	options *TopicOptions
+
+	// abandoned is closed once the topic gives up waiting for acknowledgments.
+	abandoned <-chan struct{}
+
+	// unacked counts the messages waiting to be acknowledged in the whole topic.
+	unacked *atomic.Int64
+
+	calling sync.Mutex // held while calling fn
+
+	mu      sync.Mutex
+	pending map[*pendingAck[T]]struct{}
+	idle    chan struct{} // closed once there are no pending messages, if anyone is waiting for it
+	stopped bool          // true once fn returned false
+}
+
+func newAckSubscriber[T any](fn AckSubscriber[T], options *TopicOptions, abandoned <-chan struct{}, unacked *atomic.Int64) *ackSubscriber[T] {
+	return &ackSubscriber[T]{
+		fn:        fn,
+		options:   options,
+		abandoned: abandoned,
+		unacked:   unacked,
+		pending:   make(map[*pendingAck[T]]struct{}),
+	}
+}
+
+// Receive delivers a message published to the topic for the first time.
+func (a *ackSubscriber[T]) Receive(msg T) bool {
+	a.mu.Lock()
+
+	if a.stopped {
+		a.mu.Unlock()
+		return false
+	}
+
+	p := &pendingAck[T]{msg: msg}
+	a.pending[p] = struct{}{}
+	a.unacked.Add(1)
+
+	a.mu.Unlock()
+
+	return a.deliver(p)
+}
+
+// Close waits for every pending message to be acknowledged or rejected. Messages are still
+// delivered again while waiting. If the topic gives up waiting, pending messages are handed to the
+// WithOnUndelivered callback.
+func (a *ackSubscriber[T]) Close() {
+	a.mu.Lock()
+
+	if len(a.pending) == 0 {
+		a.mu.Unlock()
+		return
+	}
+
+	if a.idle == nil {
+		a.idle = make(chan struct{})
+	}
+	idle := a.idle
+
+	a.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-a.abandoned:
+		a.abandon()
+	}
+}
+
+// deliver calls fn with a new attempt of p unless it was settled in the meantime. Returns false if
+// fn unsubscribed.
+func (a *ackSubscriber[T]) deliver(p *pendingAck[T]) bool {
+	a.calling.Lock()
+	defer a.calling.Unlock()
+
+	d, ok := a.attempt(p)
+	if !ok {
+		return true
+	}
+
+	if a.call(d) {
+		return true
+	}
+
+	a.mu.Lock()
+	a.stopped = true
+	a.mu.Unlock()
+
+	return false
+}
+
+// attempt starts a new attempt to deliver p. Returns false if p was settled in the meantime or if
+// it had to be abandoned because fn unsubscribed.
+func (a *ackSubscriber[T]) attempt(p *pendingAck[T]) (*Delivery[T], bool) {
+	a.mu.Lock()
+
+	if p.settled {
+		a.mu.Unlock()
+		return nil, false
+	}
+
+	if a.stopped {
+		a.remove(p)
+		a.mu.Unlock()
+
+		a.options.TriggerUndelivered(p.msg)
+		return nil, false
+	}
+
+	p.attempts++
+	p.scheduled = false
+
+	attempt := p.attempts
+	p.timer = time.AfterFunc(a.options.VisibilityTimeout(), func() {
+		a.redeliver(p, attempt)
+	})
+
+	a.mu.Unlock()
+
+	return &Delivery[T]{Message: p.msg, Attempt: attempt, pending: p, subscriber: a}, true
+}
+
+// call calls fn with d. A panic is recovered and handled according to the topic options. The
+// message is then delivered again once the visibility timeout expires.
+func (a *ackSubscriber[T]) call(d *Delivery[T]) (keep bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.options.TriggerPanic(r, d.Message)
+			keep = a.options.PanicPolicy() == PanicKeep
+		}
+	}()
+
+	return a.fn(d)
+}
+
+// redeliver delivers p again in the background unless it was settled, attempted again or already
+// scheduled to be delivered again.
+func (a *ackSubscriber[T]) redeliver(p *pendingAck[T], attempt int) {
+	a.mu.Lock()
+
+	if p.settled || p.scheduled || p.attempts != attempt {
+		a.mu.Unlock()
+		return
+	}
+
+	p.scheduled = true
+	p.timer.Stop()
+
+	a.mu.Unlock()
+
+	go a.deliver(p)
+}
+
+// settle marks p as processed. Returns false if it was already settled.
+func (a *ackSubscriber[T]) settle(p *pendingAck[T]) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if p.settled {
+		return false
+	}
+
+	a.remove(p)
+
+	return true
+}
+
+// stop makes sure fn is not called again, not even to deliver pending messages again. These are
+// handed to the WithOnUndelivered callback right away.
+func (a *ackSubscriber[T]) stop() {
+	a.mu.Lock()
+	a.stopped = true
+	a.mu.Unlock()
+
+	a.abandon()
+}
+
+// abandon settles every pending message and hands them to the WithOnUndelivered callback.
+func (a *ackSubscriber[T]) abandon() {
+	a.mu.Lock()
+
+	var abandoned []T
+	for p := range a.pending {
+		abandoned = append(abandoned, p.msg)
+		a.remove(p)
+	}
+
+	a.mu.Unlock()
+
+	for _, msg := range abandoned {
+		a.options.TriggerUndelivered(msg)
+	}
+}
+
+// remove settles p and stops tracking it. The lock must be held.
+func (a *ackSubscriber[T]) remove(p *pendingAck[T]) {
+	p.settled = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	delete(a.pending, p)
+	a.unacked.Add(-1)
+
+	if len(a.pending) == 0 && a.idle != nil {
+		close(a.idle)
+		a.idle = nil
+	}
+}
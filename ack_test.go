@@ -0,0 +1,198 @@
+package gubgub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeAck(t *testing.T) {
+	topic := NewAsyncTopic[int]()
+
+	var received []int
+	_, err := topic.SubscribeAck(func(d *Delivery[int]) bool {
+		assert.Equal(t, 1, d.Attempt)
+		received = append(received, d.Message)
+		d.Ack()
+		return true
+	})
+	require.NoError(t, err)
+
+	for i := range 5 {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	topic.Close()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, received)
+	assert.Zero(t, topic.unacked.Load())
+}
+
+func TestSubscribeAck_VisibilityTimeout(t *testing.T) {
+	topic := NewAsyncTopic[int](WithVisibilityTimeout(10 * time.Millisecond))
+
+	var attempts []int
+	_, err := topic.SubscribeAck(func(d *Delivery[int]) bool {
+		attempts = append(attempts, d.Attempt)
+		if d.Attempt == 3 {
+			d.Ack()
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+
+	topic.Close() // waits for the message to be acknowledged
+
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+func TestSubscribeAck_Nack(t *testing.T) {
+	deadLetters := NewSyncTopic[DeadLetter[int]]()
+	defer deadLetters.Close()
+
+	var rejected []DeadLetter[int]
+	require.NoError(t, deadLetters.Subscribe(Forever(func(dl DeadLetter[int]) {
+		rejected = append(rejected, dl)
+	})))
+
+	topic := NewAsyncTopic[int](WithDeadLetter[int](deadLetters))
+
+	var attempts []int
+	_, err := topic.SubscribeAck(func(d *Delivery[int]) bool {
+		attempts = append(attempts, d.Attempt)
+		d.Nack(d.Attempt < 2) // requeue once and then give up
+		return true
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+
+	topic.Close()
+
+	assert.Equal(t, []int{1, 2}, attempts, "expected requeued message to be delivered again right away")
+
+	require.Len(t, rejected, 1)
+	assert.Equal(t, 1, rejected[0].Message)
+	assert.ErrorIs(t, rejected[0].Err, ErrRejected)
+	assert.Equal(t, 2, rejected[0].Attempts)
+
+	var rejectedErr *RejectedError
+	require.ErrorAs(t, rejected[0].Err, &rejectedErr)
+	assert.Equal(t, 2, rejectedErr.Attempts())
+
+	var retryErr *RetryError
+	assert.False(t, errors.As(rejected[0].Err, &retryErr), "expected a rejection not to look like a retry")
+}
+
+func TestSubscribeAck_UnsubscribeUnacked(t *testing.T) {
+	undelivered := make(chan any, 1)
+
+	topic := NewAsyncTopic[int](
+		WithVisibilityTimeout(time.Millisecond),
+		WithOnUndelivered(func(msg any) { undelivered <- msg }),
+	)
+
+	var calls atomic.Int64
+	delivered := make(chan struct{}, 1)
+
+	sub, err := topic.SubscribeAck(func(d *Delivery[int]) bool {
+		calls.Add(1)
+		select {
+		case delivered <- struct{}{}:
+		default:
+		}
+		return true // never acknowledges
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+	<-delivered
+
+	sub.Unsubscribe()
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case <-sub.Done():
+	case <-timeout.C:
+		t.Fatalf("expected subscriber to be removed")
+	}
+
+	assert.Equal(t, 1, <-undelivered, "expected the unacknowledged message to be abandoned")
+
+	unsubscribed := calls.Load()
+	time.Sleep(10 * time.Millisecond) // many visibility timeouts
+
+	topic.Close()
+
+	assert.Equal(t, unsubscribed, calls.Load(), "expected no delivery after unsubscribing")
+}
+
+func TestSubscribeAck_CloseWaitsForAcks(t *testing.T) {
+	topic := NewAsyncTopic[int]()
+
+	var (
+		mu    sync.Mutex
+		acked []int
+	)
+
+	_, err := topic.SubscribeAck(func(d *Delivery[int]) bool {
+		go func() { // acknowledge asynchronously, after the subscriber returned
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			acked = append(acked, d.Message)
+			mu.Unlock()
+
+			d.Ack()
+		}()
+		return true
+	})
+	require.NoError(t, err)
+
+	for i := range 3 {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	topic.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []int{0, 1, 2}, acked)
+}
+
+func TestSubscribeAck_Shutdown(t *testing.T) {
+	var undelivered []any
+
+	topic := NewAsyncTopic[int](WithOnUndelivered(func(msg any) {
+		undelivered = append(undelivered, msg)
+	}))
+
+	_, err := topic.SubscribeAck(func(d *Delivery[int]) bool {
+		return true // never acknowledges
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+	require.NoError(t, topic.Publish(2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = topic.Shutdown(ctx)
+
+	var undeliveredErr *UndeliveredError
+	require.ErrorAs(t, err, &undeliveredErr)
+	assert.Equal(t, 2, undeliveredErr.Undelivered)
+
+	<-topic.closed // abandoned messages are handed over before the topic is closed
+	assert.ElementsMatch(t, []any{1, 2}, undelivered)
+}
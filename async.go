@@ -1,136 +1,362 @@
 package gubgub
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // AsyncTopic allows any message T to be broadcast to subscribers. Publishing as well as
 // subscribing happens asynchronously (as non-blocking as possible).
 // Closing the topic guarantees that published message will be delivered and no further messages
 // nor subscribers will be accepted.
-// Delivery order is NOT guaranteed.
+// Messages are delivered in the same order Publish was called (FIFO).
+// By default there is no limit to how many messages can wait to be delivered. Use WithQueueSize and
+// WithOverflowPolicy to bound the queue and decide what happens when it is full.
 type AsyncTopic[T any] struct {
 	options TopicOptions
 
-	mu      sync.RWMutex
-	closing bool
-	closed  chan struct{}
+	queue  *queue[T]
+	closed chan struct{}
 
-	publishCh   chan T
-	subscribeCh chan Subscriber[T]
+	// Closing waits for AckSubscribers to settle their pending messages unless Shutdown gives up
+	// waiting, in which case abandoned is closed.
+	unacked     atomic.Int64
+	releasing   atomic.Bool // true once every message was delivered and subscribers are being closed
+	abandoned   chan struct{}
+	abandonOnce sync.Once
+
+	// Subscribing and unsubscribing is queued separately from messages. The run loop applies
+	// these changes right after taking each message from the queue so that a subscriber registered
+	// before a message is published is guaranteed to get that message.
+	mu             sync.Mutex
+	closing        bool
+	subscribed     []*subscription[T] // subscriptions waiting to be added
+	unsubscribed   []*subscription[T] // subscriptions waiting to be removed
+	replays        []func(*latest[T]) // funcs waiting to be called with the latest message
+	pendingChanges atomic.Bool        // true if there are subscriptions waiting to be added or removed
+
+	groups consumerGroups[T]
+	types  typeRoutes[T]
+	latest latest[T] // replayed to new subscribers, see WithReplayLatest. Owned by the run loop.
 }
 
 // NewAsyncTopic creates an AsyncTopic.
 func NewAsyncTopic[T any](opts ...TopicOption) *AsyncTopic[T] {
 	t := AsyncTopic[T]{
-		closed:      make(chan struct{}),
-		publishCh:   make(chan T, 1),
-		subscribeCh: make(chan Subscriber[T], 1),
+		closed:    make(chan struct{}),
+		abandoned: make(chan struct{}),
 	}
 
 	t.SetOptions(opts...)
 
+	t.queue = newQueue[T](t.options.QueueSize(), t.options.Overflow())
+
 	go t.run()
 
 	return &t
 }
 
 // Close terminates background go routines and prevents further publishing and subscribing. All
-// published messages are guaranteed to be delivered once Close returns. This is idempotent and
-// thread safe.
+// published messages are guaranteed to be delivered and closable subscribers are closed once Close
+// returns. This includes waiting for AckSubscribers to acknowledge or reject every message. This is
+// idempotent and thread safe.
 func (t *AsyncTopic[T]) Close() {
-	t.mu.Lock()
-	if t.closing {
-		// Multiple go routines attempted to close this topic. All should wait for the topic to be
-		// closed before returning.
-		t.mu.Unlock()
-		<-t.closed
-		return
+	t.close()
+
+	// Multiple go routines might attempt to close this topic. All should wait for the topic to be
+	// closed before returning.
+	<-t.closed
+}
+
+// Shutdown prevents further publishing and subscribing and then waits for queued messages to be
+// delivered just like Close. However, if ctx is done first, messages still in the queue as well as
+// messages AckSubscribers didn't acknowledge yet are abandoned and handed to the WithOnUndelivered
+// callback. In that case an UndeliveredError reports how many messages were not delivered,
+// including the one being delivered, if any. Background go routines terminate once the message
+// being delivered is done. An error wrapping ctx.Err() is also returned if closing the closable
+// subscribers is not done before ctx is.
+func (t *AsyncTopic[T]) Shutdown(ctx context.Context) error {
+	t.close()
+
+	select {
+	case <-t.closed:
+		return nil
+	case <-ctx.Done():
 	}
 
-	t.closing = true // no more subscribing or publishing
-	t.mu.Unlock()
+	abandoned := t.queue.Drain()
 
-	close(t.publishCh)
-	close(t.subscribeCh)
+	for _, msg := range abandoned {
+		t.options.TriggerUndelivered(msg)
+	}
 
-	<-t.closed
+	undelivered := len(abandoned) + int(t.unacked.Load())
+
+	t.abandonOnce.Do(func() {
+		close(t.abandoned)
+	})
+
+	select {
+	case <-t.closed:
+		if undelivered == 0 {
+			return nil
+		}
+
+	default:
+		if !t.releasing.Load() {
+			undelivered++ // the queue is empty so the run loop must be stuck delivering a message
+		} else if undelivered == 0 {
+			// Every message was delivered but the run loop is stuck closing a subscriber.
+			return fmt.Errorf("async topic shutdown: %w", ctx.Err())
+		}
+	}
+
+	return fmt.Errorf("async topic shutdown: %w", &UndeliveredError{Undelivered: undelivered, Err: ctx.Err()})
+}
+
+// close prevents further publishing and subscribing.
+func (t *AsyncTopic[T]) close() {
+	t.mu.Lock()
+	t.closing = true // no more subscribing
+	t.mu.Unlock()
+
+	t.queue.Close() // no more publishing
 }
 
 func (t *AsyncTopic[T]) run() {
 	defer close(t.closed)
 	defer t.options.TriggerClose()
 
-	var subscribers []Subscriber[T]
+	var subscribers []*subscription[T]
 
-	defer func() {
-		// There is only one way to get here: the topic is now closing!
-		// Because both `subscribeCh` and `publishCh` channels are closed when the topic is closed
-		// we can assume this will always eventually return.
-		// This will deliver any potential queued message thus fulfilling the message delivery
-		// promise.
-		for msg := range t.publishCh {
-			subscribers = sequentialDelivery(msg, subscribers)
-		}
-	}()
+	deliverQueued := func() {
+		for {
+			msg, ok := t.queue.Pop()
+
+			// Subscription changes must be applied after taking the message.
+			subscribers = t.applyChanges(subscribers)
 
-	for {
-		select {
-		case newCallback, more := <-t.subscribeCh:
-			if !more {
+			if !ok {
 				return
 			}
 
-			subscribers = append(subscribers, newCallback)
-			t.options.TriggerSubscribe()
+			subscribers = strategyDelivery(t.options.Delivery(), msg, subscribers)
 
-		case msg, more := <-t.publishCh:
-			if !more {
-				return
+			if t.options.ReplayLatest() {
+				t.latest.set(msg)
 			}
+		}
+	}
+
+	for range t.queue.Wake() {
+		deliverQueued()
+	}
+
+	// There is only one way to get here: the topic is now closing!
+	// Because the queue is closed no more messages can be published so we can assume this will
+	// always eventually return. This will deliver any potential queued message thus fulfilling the
+	// message delivery promise.
+	deliverQueued()
+
+	t.releasing.Store(true)
+	subscribers = releaseSubscriptions(subscribers)
+}
+
+// applyChanges adds and removes subscriptions waiting to be added or removed.
+func (t *AsyncTopic[T]) applyChanges(subscribers []*subscription[T]) []*subscription[T] {
+	if !t.pendingChanges.Load() {
+		return subscribers
+	}
 
-			subscribers = sequentialDelivery(msg, subscribers)
+	t.mu.Lock()
+	subscribed, unsubscribed, replays := t.subscribed, t.unsubscribed, t.replays
+	t.subscribed, t.unsubscribed, t.replays = nil, nil, nil
+	t.pendingChanges.Store(false)
+	t.mu.Unlock()
+
+	for _, s := range subscribed {
+		if t.latest.replay(s) {
+			subscribers = addSubscription(subscribers, s)
+		} else {
+			s.release()
 		}
+
+		t.options.TriggerSubscribe()
+	}
+
+	for _, fn := range replays {
+		fn(&t.latest)
 	}
+
+	for _, s := range unsubscribed {
+		subscribers = removeSubscription(subscribers, s)
+	}
+
+	return subscribers
 }
 
-// Publish broadcasts a msg to all subscribers asynchronously.
+// Publish broadcasts a msg to all subscribers asynchronously. Messages are queued and delivered in
+// the same order Publish was called, even across different publishing go routines.
+// If the queue is bounded and full then the overflow policy decides whether this blocks, drops a
+// message or returns ErrTopicFull. Blocking publishers must not be subscribers of the same topic
+// otherwise they would be waiting for themselves.
 func (t *AsyncTopic[T]) Publish(msg T) error {
-	t.mu.RLock()
+	return t.publish(context.Background(), msg)
+}
 
-	// We hold the Read lock until we are done with publishing to avoid panic due to a closed channel.
+// PublishContext broadcasts a msg to all subscribers asynchronously unless ctx is already done in
+// which case ctx.Err() is returned. If the queue is full and the overflow policy is to block then
+// this gives up waiting for room in the queue once ctx is done.
+func (t *AsyncTopic[T]) PublishContext(ctx context.Context, msg T) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("async topic publish: %w", err)
+	}
+
+	return t.publish(ctx, msg)
+}
+
+func (t *AsyncTopic[T]) publish(ctx context.Context, msg T) error {
+	if err := t.queue.Push(ctx, msg); err != nil {
+		return fmt.Errorf("async topic publish: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe registers a Subscriber func asynchronously. Even though the subscriber might not be
+// registered yet when this returns, it is guaranteed to get every message published afterwards.
+func (t *AsyncTopic[T]) Subscribe(fn Subscriber[T]) error {
+	_, err := t.SubscribeWithHandle(fn)
+	return err
+}
+
+// SubscribeWithHandle registers a Subscriber func asynchronously and returns a Subscription that can
+// be used to remove it. It is safe to unsubscribe even before the subscriber is registered.
+func (t *AsyncTopic[T]) SubscribeWithHandle(fn Subscriber[T]) (Subscription, error) {
+	s := newSubscription(fn, &t.options, t.unsubscribe)
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SubscribeErr registers an ErrorSubscriber func asynchronously and returns a Subscription that can
+// be used to remove it. Failures are reported to the WithOnError handler.
+func (t *AsyncTopic[T]) SubscribeErr(fn ErrorSubscriber[T]) (Subscription, error) {
+	s := newErrSubscription(fn, &t.options, t.unsubscribe)
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SubscribeClosable registers a ClosableSubscriber asynchronously and returns a Subscription
+// that can be used to remove it. The subscriber is closed once removed.
+func (t *AsyncTopic[T]) SubscribeClosable(sub ClosableSubscriber[T]) (Subscription, error) {
+	s := newClosableSubscription(sub, &t.options, t.unsubscribe)
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SubscribeAck registers an AckSubscriber asynchronously and returns a Subscription that can be used
+// to remove it. Messages are delivered at least once: each message is delivered again until the
+// subscriber acknowledges or rejects it. See WithVisibilityTimeout.
+func (t *AsyncTopic[T]) SubscribeAck(fn AckSubscriber[T]) (Subscription, error) {
+	a := newAckSubscriber(fn, &t.options, t.abandoned, &t.unacked)
+	s := newClosableSubscription[T](a, &t.options, func(s *subscription[T]) {
+		a.stop() // messages must not be delivered again once unsubscribed
+		t.unsubscribe(s)
+	})
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SubscribeGroup registers a Subscriber func as a member of the named consumer group
+// asynchronously and returns a Subscription that can be used to remove it. Each message is delivered
+// to only one member of the group, in turns. A member that returns false leaves the group and the
+// group is removed once it has no members left.
+func (t *AsyncTopic[T]) SubscribeGroup(name string, fn Subscriber[T]) (Subscription, error) {
+	return t.groups.join(name, fn, &t.options, t.subscribe, t.unsubscribe)
+}
+
+// SubscribeType registers a Subscriber func that will only consume messages whose dynamic
+// type is typ, or implements typ if it is an interface. It returns a Subscription that can be used
+// to remove it. See SubscribeAs for a type safe alternative.
+func (t *AsyncTopic[T]) SubscribeType(typ reflect.Type, fn Subscriber[T]) (Subscription, error) {
+	return t.types.subscribe(typ, fn, &t.options, t.subscribe, t.unsubscribe, t.withLatest)
+}
+
+// SubscribeContext registers a Subscriber func asynchronously. The subscriber consumes messages
+// until it returns false or ctx is done, whichever happens first.
+func (t *AsyncTopic[T]) SubscribeContext(ctx context.Context, fn Subscriber[T]) error {
+	s := newSubscription(fn, &t.options, t.unsubscribe)
+	s.bind(ctx)
+
+	return t.subscribe(s)
+}
+
+func (t *AsyncTopic[T]) subscribe(s *subscription[T]) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	if t.closing {
-		t.mu.RUnlock()
-		return fmt.Errorf("async topic publish: %w", ErrTopicClosed)
+		s.detach() // never part of the topic so the subscriber is left alone
+		return fmt.Errorf("async topic subscribe: %w", ErrTopicClosed)
 	}
 
-	go func() {
-		t.publishCh <- msg
-		t.mu.RUnlock()
-	}()
+	t.subscribed = append(t.subscribed, s)
+	t.pendingChanges.Store(true)
+	t.queue.Signal()
 
 	return nil
 }
 
-// Subscribe registers a Subscriber func asynchronously.
-func (t *AsyncTopic[T]) Subscribe(fn Subscriber[T]) error {
-	t.mu.RLock()
+// withLatest queues fn to be called by the run loop with the message to replay to new subscribers,
+// along with the other subscription changes.
+func (t *AsyncTopic[T]) withLatest(fn func(*latest[T])) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	if t.closing {
-		t.mu.RUnlock()
 		return fmt.Errorf("async topic subscribe: %w", ErrTopicClosed)
 	}
 
-	go func() {
-		t.subscribeCh <- fn
-		t.mu.RUnlock()
-	}()
+	t.replays = append(t.replays, fn)
+	t.pendingChanges.Store(true)
+	t.queue.Signal()
 
 	return nil
 }
 
+// unsubscribe queues s to be removed by the run loop.
+func (t *AsyncTopic[T]) unsubscribe(s *subscription[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closing {
+		return // all subscriptions are removed once the topic is closed
+	}
+
+	t.unsubscribed = append(t.unsubscribed, s)
+	t.pendingChanges.Store(true)
+	t.queue.Signal()
+}
+
 func (t *AsyncTopic[T]) SetOptions(opts ...TopicOption) {
 	t.options.Apply(opts...)
 }
@@ -19,6 +19,7 @@ func BenchmarkAsyncTopic_Publish(b *testing.B) {
 
 			<-subscribersReady
 
+			b.ReportAllocs()
 			b.ResetTimer()
 
 			for i := range b.N {
@@ -30,5 +31,49 @@ func BenchmarkAsyncTopic_Publish(b *testing.B) {
 			topic.Close()
 		})
 	}
+}
+
+func BenchmarkAsyncTopic_PublishParallel(b *testing.B) {
+	for _, tc := range publishCases {
+		b.Run(tc.Name, func(b *testing.B) {
+			onSubscribe, subscribersReady := withNotifyOnNthSubscriber(b, int64(tc.Count))
+			topic := NewAsyncTopic[int](onSubscribe)
+			b.Cleanup(topic.Close)
+
+			for range tc.Count {
+				require.NoError(b, topic.Subscribe(tc.Subscriber))
+			}
+
+			<-subscribersReady
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					_ = topic.Publish(i)
+				}
+			})
+
+			b.StopTimer()
+
+			topic.Close()
+		})
+	}
+}
+
+func BenchmarkAsyncTopic_Subscribe(b *testing.B) {
+	topic := NewAsyncTopic[int]()
+	b.Cleanup(topic.Close)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		_ = topic.Subscribe(NoOp[int]())
+	}
+
+	b.StopTimer()
 
+	topic.Close()
 }
@@ -1,6 +1,7 @@
 package gubgub
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -185,6 +186,283 @@ func TestAsyncTopic_AllPublishedBeforeClosedAreDelivered(t *testing.T) {
 	}
 }
 
+func TestAsyncTopic_DeliveryOrder(t *testing.T) {
+	const msgCount = 1000
+
+	onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+	topic := NewAsyncTopic[int](onSubscribe)
+	t.Cleanup(topic.Close)
+
+	var feedback []int
+
+	err := topic.Subscribe(func(i int) bool {
+		feedback = append(feedback, i)
+		return true
+	})
+	require.NoError(t, err)
+
+	<-subscriberReady
+
+	for i := range msgCount {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	topic.Close()
+
+	require.Len(t, feedback, msgCount)
+	for i, f := range feedback {
+		require.Equal(t, i, f, "expected messages to be delivered in publishing order")
+	}
+}
+
+func TestAsyncTopic_SubscribedBeforePublished(t *testing.T) {
+	for range 100 {
+		topic := NewAsyncTopic[int]()
+
+		var feedback []int
+
+		require.NoError(t, topic.Subscribe(Forever(func(i int) {
+			feedback = append(feedback, i)
+		})))
+
+		require.NoError(t, topic.Publish(1)) // no waiting for the subscriber to be registered
+
+		topic.Close()
+
+		require.Equal(t, []int{1}, feedback)
+	}
+}
+
+func TestAsyncTopic_PublishContext(t *testing.T) {
+	topic := NewAsyncTopic[int]()
+	t.Cleanup(topic.Close)
+
+	require.NoError(t, topic.PublishContext(context.Background(), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, topic.PublishContext(ctx, 2), context.Canceled)
+}
+
+func TestAsyncTopic_QueueOverflow(t *testing.T) {
+	const queueSize = 2
+
+	testCases := []struct {
+		name         string
+		policy       OverflowPolicy
+		assertFn     func(t *testing.T, topic *AsyncTopic[int])
+		expDelivered []int
+	}{
+		{
+			name:   "block",
+			policy: OverflowBlock,
+			assertFn: func(t *testing.T, topic *AsyncTopic[int]) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+
+				assert.ErrorIs(t, topic.PublishContext(ctx, 3), context.DeadlineExceeded)
+			},
+			expDelivered: []int{0, 1, 2},
+		},
+		{
+			name:   "drop newest",
+			policy: OverflowDropNewest,
+			assertFn: func(t *testing.T, topic *AsyncTopic[int]) {
+				assert.NoError(t, topic.Publish(3))
+			},
+			expDelivered: []int{0, 1, 2},
+		},
+		{
+			name:   "drop oldest",
+			policy: OverflowDropOldest,
+			assertFn: func(t *testing.T, topic *AsyncTopic[int]) {
+				assert.NoError(t, topic.Publish(3))
+			},
+			expDelivered: []int{0, 2, 3},
+		},
+		{
+			name:   "return error",
+			policy: OverflowReturnError,
+			assertFn: func(t *testing.T, topic *AsyncTopic[int]) {
+				assert.ErrorIs(t, topic.Publish(3), ErrTopicFull)
+			},
+			expDelivered: []int{0, 1, 2},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+			topic := NewAsyncTopic[int](onSubscribe, WithQueueSize(queueSize), WithOverflowPolicy(tc.policy))
+			t.Cleanup(topic.Close)
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+
+			var delivered []int
+
+			err := topic.Subscribe(Forever(func(i int) {
+				if i == 0 {
+					close(started)
+					<-release // hold the first message so that the queue fills up
+				}
+				delivered = append(delivered, i)
+			}))
+			require.NoError(t, err)
+
+			<-subscriberReady
+
+			require.NoError(t, topic.Publish(0))
+			<-started
+
+			for i := 1; i <= queueSize; i++ {
+				require.NoError(t, topic.Publish(i))
+			}
+
+			tc.assertFn(t, topic)
+
+			close(release)
+			topic.Close()
+
+			assert.Equal(t, tc.expDelivered, delivered)
+		})
+	}
+}
+
+func TestAsyncTopic_QueueBlockUntilRoom(t *testing.T) {
+	onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+	topic := NewAsyncTopic[int](onSubscribe, WithQueueSize(1))
+	t.Cleanup(topic.Close)
+
+	release := make(chan struct{})
+	feedback := make(chan int, 3)
+
+	err := topic.Subscribe(Forever(func(i int) {
+		<-release
+		feedback <- i
+	}))
+	require.NoError(t, err)
+
+	<-subscriberReady
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := range 3 {
+			assert.NoError(t, topic.Publish(i))
+		}
+	}()
+
+	select {
+	case <-published:
+		t.Fatalf("expected publishing to block while the queue is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-published:
+	case <-testTimer(t, time.Second).C:
+		t.Fatalf("expected publishing to resume by now")
+	}
+
+	topic.Close()
+
+	assert.Equal(t, 0, <-feedback)
+	assert.Equal(t, 1, <-feedback)
+	assert.Equal(t, 2, <-feedback)
+}
+
+func TestAsyncTopic_Shutdown(t *testing.T) {
+	topic := NewAsyncTopic[int]()
+
+	var feedback []int
+
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		feedback = append(feedback, i)
+	})))
+
+	for i := range 10 {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, topic.Shutdown(ctx))
+
+	assert.Len(t, feedback, 10, "expected all messages to be delivered")
+	assert.ErrorIs(t, topic.Publish(10), ErrTopicClosed)
+}
+
+func TestAsyncTopic_ShutdownDeadline(t *testing.T) {
+	var undelivered []any
+
+	topic := NewAsyncTopic[int](WithOnUndelivered(func(msg any) {
+		undelivered = append(undelivered, msg)
+	}))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		if i == 0 {
+			close(started)
+			<-release // wedged subscriber
+		}
+	})))
+
+	for i := range 4 {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := topic.Shutdown(ctx)
+
+	var undeliveredErr *UndeliveredError
+	require.ErrorAs(t, err, &undeliveredErr)
+	assert.Equal(t, 4, undeliveredErr.Undelivered, "expected the message in progress and the queued ones")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Equal(t, []any{1, 2, 3}, undelivered)
+}
+
+func TestAsyncTopic_ShutdownWedgedClose(t *testing.T) {
+	topic := NewAsyncTopic[int]()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := topic.SubscribeClosable(closableFunc[int]{
+		receive: NoOp[int](),
+		close:   func() { <-release }, // never returns in time
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- topic.Shutdown(ctx)
+	}()
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case err := <-shutdown:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-timeout.C:
+		t.Fatalf("expected shutdown to give up once its context is done")
+	}
+}
+
 func testTimer(t testing.TB, d time.Duration) *time.Timer {
 	t.Helper()
 
@@ -0,0 +1,348 @@
+package gubgub
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TopicKey is a type safe handle to a topic owned by a Broker. Topic names are made of tokens
+// separated by dots, like "orders.eu.created".
+type TopicKey[T any] struct {
+	name string
+}
+
+// NewTopicKey creates a TopicKey for the topic with the given name. Keys are cheap values so they can
+// be declared once and shared.
+func NewTopicKey[T any](name string) TopicKey[T] {
+	return TopicKey[T]{name: name}
+}
+
+// Name returns the name of the topic.
+func (k TopicKey[T]) Name() string {
+	return k.name
+}
+
+// BrokerOptions holds the options of a Broker.
+type BrokerOptions struct {
+	async        bool
+	topicOptions []TopicOption
+}
+
+// BrokerOption sets an option of a Broker.
+type BrokerOption func(*BrokerOptions)
+
+// WithAsyncTopics makes the broker create AsyncTopics instead of SyncTopics.
+func WithAsyncTopics() BrokerOption {
+	return func(opts *BrokerOptions) {
+		opts.async = true
+	}
+}
+
+// WithTopicDefaults sets the options every topic is created with.
+func WithTopicDefaults(opts ...TopicOption) BrokerOption {
+	return func(o *BrokerOptions) {
+		o.topicOptions = append(o.topicOptions, opts...)
+	}
+}
+
+// Broker owns topics by name. Topics are created the first time they are used and closed when the
+// broker is closed. Besides subscribing to a single topic, a broker allows subscribing to every
+// topic whose name matches a pattern:
+//
+//   - "*" matches exactly one token: "orders.*.created" matches "orders.eu.created".
+//   - ">" matches one or more tokens and must be the last token: "orders.>" matches
+//     "orders.eu.created" and "orders.us.cancelled".
+type Broker struct {
+	options BrokerOptions
+
+	mu       sync.Mutex
+	closed   bool
+	topics   map[string]*brokerTopic
+	patterns []*patternSubscription
+}
+
+// brokerTopic is a topic owned by a Broker. The topic is kept as any since each topic has its own
+// message type.
+type brokerTopic struct {
+	topic  any // Topic[T]
+	closer Closer
+
+	// subscribe adds a subscriber that doesn't care about the message type.
+	subscribe func(fn func(msg any) bool) (Subscription, error)
+}
+
+// NewBroker creates a Broker with the specified options.
+func NewBroker(opts ...BrokerOption) *Broker {
+	b := &Broker{
+		topics: make(map[string]*brokerTopic),
+	}
+
+	for _, opt := range opts {
+		opt(&b.options)
+	}
+
+	return b
+}
+
+// TopicOf returns the topic of the broker identified by key, creating it if needed. It fails with
+// ErrTopicType if the topic already exists with a different message type and with ErrTopicClosed if
+// the broker is closed.
+func TopicOf[T any](b *Broker, key TopicKey[T]) (Topic[T], error) {
+	if err := validateTopicName(key.name); err != nil {
+		return nil, fmt.Errorf("broker topic: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, fmt.Errorf("broker topic: %w", ErrTopicClosed)
+	}
+
+	if bt, ok := b.topics[key.name]; ok {
+		topic, ok := bt.topic.(Topic[T])
+		if !ok {
+			return nil, fmt.Errorf("broker topic %q: %w", key.name, ErrTopicType)
+		}
+
+		return topic, nil
+	}
+
+	var topic Topic[T]
+	if b.options.async {
+		topic = NewAsyncTopic[T](b.options.topicOptions...)
+	} else {
+		topic = NewSyncTopic[T](b.options.topicOptions...)
+	}
+
+	bt := &brokerTopic{
+		topic:  topic,
+		closer: topic,
+		subscribe: func(fn func(msg any) bool) (Subscription, error) {
+			return topic.SubscribeWithHandle(func(msg T) bool {
+				return fn(msg)
+			})
+		},
+	}
+
+	// Nobody else knows about this topic yet so subscribing can't block.
+	for _, ps := range b.patterns {
+		if ps.matches(key.name) {
+			ps.subscribeTo(key.name, bt)
+		}
+	}
+
+	b.topics[key.name] = bt
+
+	return topic, nil
+}
+
+// PublishTo publishes msg to the topic of the broker identified by key.
+func PublishTo[T any](b *Broker, key TopicKey[T], msg T) error {
+	topic, err := TopicOf(b, key)
+	if err != nil {
+		return err
+	}
+
+	return topic.Publish(msg)
+}
+
+// SubscribeTo subscribes fn to the topic of the broker identified by key.
+func SubscribeTo[T any](b *Broker, key TopicKey[T], fn Subscriber[T]) (Subscription, error) {
+	topic, err := TopicOf(b, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return topic.SubscribeWithHandle(fn)
+}
+
+// SubscribePattern subscribes fn to every topic whose name matches pattern, including topics created
+// afterwards. The fn gets the name of the topic and the message. Since topics have different message
+// types, the message is handed over as any. The fn might be called concurrently for messages of
+// different topics. Once fn returns false it is unsubscribed from every topic.
+func (b *Broker) SubscribePattern(pattern string, fn func(topic string, msg any) bool) (Subscription, error) {
+	tokens, err := parsePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("broker subscribe: %w", err)
+	}
+
+	ps := &patternSubscription{
+		tokens: tokens,
+		fn:     fn,
+		broker: b,
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("broker subscribe: %w", ErrTopicClosed)
+	}
+
+	b.patterns = append(b.patterns, ps)
+
+	matching := make(map[string]*brokerTopic)
+	for name, bt := range b.topics {
+		if ps.matches(name) {
+			matching[name] = bt
+		}
+	}
+
+	b.mu.Unlock()
+
+	// Existing topics might be busy delivering messages so subscribing must not hold the broker
+	// lock. Topics created in the meantime already know about this pattern.
+	for name, bt := range matching {
+		ps.subscribeTo(name, bt)
+	}
+
+	return ps, nil
+}
+
+// Close closes every topic of the broker and removes every pattern subscription. Further attempts to
+// use the broker fail with ErrTopicClosed. This is idempotent and thread safe.
+func (b *Broker) Close() {
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+
+	b.closed = true
+	topics, patterns := b.topics, b.patterns
+	b.topics, b.patterns = nil, nil
+
+	b.mu.Unlock()
+
+	for _, bt := range topics {
+		bt.closer.Close()
+	}
+
+	for _, ps := range patterns {
+		ps.Unsubscribe()
+	}
+}
+
+// forget removes ps from the pattern subscriptions.
+func (b *Broker) forget(ps *patternSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.patterns = slices.DeleteFunc(b.patterns, func(p *patternSubscription) bool {
+		return p == ps
+	})
+}
+
+// patternSubscription is subscribed to every topic matching its pattern.
+type patternSubscription struct {
+	tokens []string
+	fn     func(topic string, msg any) bool
+	broker *Broker
+
+	mu           sync.Mutex
+	subs         []Subscription
+	unsubscribed bool
+	done         chan struct{}
+}
+
+// matches reports whether the topic name matches the pattern.
+func (ps *patternSubscription) matches(name string) bool {
+	tokens := strings.Split(name, ".")
+
+	for i, token := range ps.tokens {
+		switch {
+		case token == ">":
+			return len(tokens) > i
+		case i >= len(tokens):
+			return false
+		case token != "*" && token != tokens[i]:
+			return false
+		}
+	}
+
+	return len(tokens) == len(ps.tokens)
+}
+
+// subscribeTo subscribes the pattern to a topic. Errors are ignored since they only happen if the
+// topic is closed, in which case there is nothing left to get from it.
+func (ps *patternSubscription) subscribeTo(name string, bt *brokerTopic) {
+	sub, err := bt.subscribe(func(msg any) bool {
+		if ps.fn(name, msg) {
+			return true
+		}
+
+		ps.Unsubscribe()
+		return false
+	})
+	if err != nil {
+		return
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.unsubscribed {
+		sub.Unsubscribe()
+		return
+	}
+
+	ps.subs = append(ps.subs, sub)
+}
+
+// Unsubscribe removes the pattern subscription from every topic.
+func (ps *patternSubscription) Unsubscribe() {
+	ps.mu.Lock()
+
+	if ps.unsubscribed {
+		ps.mu.Unlock()
+		return
+	}
+
+	ps.unsubscribed = true
+	subs := ps.subs
+	ps.subs = nil
+	close(ps.done)
+
+	ps.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+
+	ps.broker.forget(ps)
+}
+
+// Done returns a channel that is closed once the pattern subscription is removed, either because it
+// unsubscribed or because the broker is closed.
+func (ps *patternSubscription) Done() <-chan struct{} {
+	return ps.done
+}
+
+// validateTopicName makes sure the name has no empty tokens nor wildcards.
+func validateTopicName(name string) error {
+	for token := range strings.SplitSeq(name, ".") {
+		if token == "" || token == "*" || token == ">" {
+			return fmt.Errorf("invalid topic name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// parsePattern splits a pattern in tokens making sure ">" can only be the last token.
+func parsePattern(pattern string) ([]string, error) {
+	tokens := strings.Split(pattern, ".")
+
+	for i, token := range tokens {
+		if token == "" || (token == ">" && i != len(tokens)-1) {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+
+	return tokens, nil
+}
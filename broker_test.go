@@ -0,0 +1,184 @@
+package gubgub
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderCreated struct {
+	ID string
+}
+
+type orderCancelled struct {
+	ID     string
+	Reason string
+}
+
+var (
+	euOrderCreated   = NewTopicKey[orderCreated]("orders.eu.created")
+	usOrderCreated   = NewTopicKey[orderCreated]("orders.us.created")
+	euOrderCancelled = NewTopicKey[orderCancelled]("orders.eu.cancelled")
+)
+
+func TestBroker(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []BrokerOption
+	}{
+		{
+			name: "sync topics",
+		},
+		{
+			name: "async topics",
+			opts: []BrokerOption{WithAsyncTopics()},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker(tc.opts...)
+
+			var (
+				mu      sync.Mutex
+				created []orderCreated
+				matched = make(map[string][]any)
+			)
+
+			_, err := SubscribeTo(b, euOrderCreated, Forever(func(o orderCreated) {
+				mu.Lock()
+				defer mu.Unlock()
+				created = append(created, o)
+			}))
+			require.NoError(t, err)
+
+			for _, pattern := range []string{"orders.*.created", "orders.>", "orders.eu.*"} {
+				_, err := b.SubscribePattern(pattern, func(topic string, msg any) bool {
+					mu.Lock()
+					defer mu.Unlock()
+					matched[pattern] = append(matched[pattern], msg)
+					return true
+				})
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, PublishTo(b, euOrderCreated, orderCreated{ID: "1"}))
+			require.NoError(t, PublishTo(b, usOrderCreated, orderCreated{ID: "2"})) // created after subscribing
+			require.NoError(t, PublishTo(b, euOrderCancelled, orderCancelled{ID: "1", Reason: "changed my mind"}))
+
+			b.Close()
+
+			assert.Equal(t, []orderCreated{{ID: "1"}}, created)
+			assert.ElementsMatch(t, []any{orderCreated{ID: "1"}, orderCreated{ID: "2"}}, matched["orders.*.created"])
+			assert.ElementsMatch(t, []any{
+				orderCreated{ID: "1"},
+				orderCreated{ID: "2"},
+				orderCancelled{ID: "1", Reason: "changed my mind"},
+			}, matched["orders.>"])
+			assert.ElementsMatch(t, []any{
+				orderCreated{ID: "1"},
+				orderCancelled{ID: "1", Reason: "changed my mind"},
+			}, matched["orders.eu.*"])
+		})
+	}
+}
+
+func TestBroker_TopicType(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+
+	topic, err := TopicOf(b, euOrderCreated)
+	require.NoError(t, err)
+
+	same, err := TopicOf(b, NewTopicKey[orderCreated]("orders.eu.created"))
+	require.NoError(t, err)
+	assert.Same(t, topic, same)
+
+	_, err = TopicOf(b, NewTopicKey[int]("orders.eu.created"))
+	assert.ErrorIs(t, err, ErrTopicType)
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := NewBroker()
+
+	topic, err := TopicOf(b, euOrderCreated)
+	require.NoError(t, err)
+
+	sub, err := b.SubscribePattern("orders.>", func(string, any) bool { return true })
+	require.NoError(t, err)
+
+	b.Close()
+	b.Close() // idempotent
+
+	assertClosed(t, sub.Done())
+	assert.ErrorIs(t, topic.Publish(orderCreated{}), ErrTopicClosed)
+	assert.ErrorIs(t, PublishTo(b, euOrderCreated, orderCreated{}), ErrTopicClosed)
+
+	_, err = b.SubscribePattern("orders.>", func(string, any) bool { return true })
+	assert.ErrorIs(t, err, ErrTopicClosed)
+}
+
+func TestBroker_PatternUnsubscribe(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+
+	var calls int
+	sub, err := b.SubscribePattern("orders.*.created", func(string, any) bool {
+		calls++
+		return false
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, PublishTo(b, euOrderCreated, orderCreated{ID: "1"}))
+	require.NoError(t, PublishTo(b, euOrderCreated, orderCreated{ID: "2"}))
+	require.NoError(t, PublishTo(b, usOrderCreated, orderCreated{ID: "3"}))
+
+	assert.Equal(t, 1, calls, "expected the pattern to be removed from every topic")
+	assertClosed(t, sub.Done())
+	assert.Empty(t, b.patterns)
+}
+
+func TestPatternSubscription_Matches(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "orders.eu.created", name: "orders.eu.created", want: true},
+		{pattern: "orders.eu.created", name: "orders.us.created", want: false},
+		{pattern: "orders.*.created", name: "orders.eu.created", want: true},
+		{pattern: "orders.*.created", name: "orders.eu.cancelled", want: false},
+		{pattern: "orders.*", name: "orders.eu.created", want: false},
+		{pattern: "orders.>", name: "orders.eu.created", want: true},
+		{pattern: "orders.>", name: "orders.eu", want: true},
+		{pattern: "orders.>", name: "orders", want: false},
+		{pattern: ">", name: "orders", want: true},
+		{pattern: "*.eu.>", name: "orders.eu.created", want: true},
+		{pattern: "*.eu.>", name: "orders.us.created", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			tokens, err := parsePattern(tc.pattern)
+			require.NoError(t, err)
+
+			ps := &patternSubscription{tokens: tokens}
+			assert.Equal(t, tc.want, ps.matches(tc.name))
+		})
+	}
+}
+
+func TestBroker_InvalidNames(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+
+	for _, name := range []string{"", "orders..created", "orders.*", "orders.>"} {
+		_, err := TopicOf(b, NewTopicKey[int](name))
+		assert.Error(t, err, name)
+	}
+
+	for _, pattern := range []string{"", "orders..created", "orders.>.created"} {
+		_, err := b.SubscribePattern(pattern, func(string, any) bool { return true })
+		assert.Error(t, err, pattern)
+	}
+}
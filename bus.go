@@ -0,0 +1,34 @@
+package gubgub
+
+// Bus routes events published to a single Topic[any] to the handlers registered for their type. This
+// replaces subscribers switching over the type of each event.
+//
+// Handlers are registered in the underlying topic with SubscribeAs so the bus has the delivery and
+// closing semantics of that topic: use a SyncTopic to publish synchronously or an AsyncTopic to
+// publish asynchronously. Subscribers that get every event can still be added to the bus directly.
+type Bus struct {
+	Topic[any]
+
+	types TypeSubscribable[any]
+}
+
+// BusTopic is a topic a Bus can be created on top of. Both SyncTopic and AsyncTopic implement it.
+type BusTopic interface {
+	Topic[any]
+	TypeSubscribable[any]
+}
+
+// NewBus creates a Bus on top of topic.
+func NewBus(topic BusTopic) *Bus {
+	return &Bus{Topic: topic, types: topic}
+}
+
+// Handle registers fn to handle every event published to the bus whose dynamic type is E. If E is an
+// interface then fn handles every event implementing it. An event is handed to every matching
+// handler.
+func Handle[E any](bus *Bus, fn func(E)) (Subscription, error) {
+	return SubscribeAs(bus.types, func(event E) bool {
+		fn(event)
+		return true
+	})
+}
@@ -0,0 +1,83 @@
+package gubgub
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userCreated struct {
+	Name string
+}
+
+func (e userCreated) String() string {
+	return "user created: " + e.Name
+}
+
+type userDeleted struct {
+	Name string
+}
+
+func TestBus(t *testing.T) {
+	for _, tc := range topicCases[any]() {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := NewBus(tc.newTopic())
+
+			var (
+				created  []userCreated
+				deleted  []userDeleted
+				stringer []string
+				all      []any
+			)
+
+			_, err := Handle(bus, func(e userCreated) {
+				created = append(created, e)
+			})
+			require.NoError(t, err)
+
+			_, err = Handle(bus, func(e userDeleted) {
+				deleted = append(deleted, e)
+			})
+			require.NoError(t, err)
+
+			_, err = Handle(bus, func(e fmt.Stringer) {
+				stringer = append(stringer, e.String())
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, bus.Subscribe(Forever(func(e any) {
+				all = append(all, e)
+			})))
+
+			require.NoError(t, bus.Publish(userCreated{Name: "alice"}))
+			require.NoError(t, bus.Publish(userDeleted{Name: "bob"}))
+			require.NoError(t, bus.Publish(42)) // nobody handles ints specifically
+
+			bus.Close()
+
+			assert.Equal(t, []userCreated{{Name: "alice"}}, created)
+			assert.Equal(t, []userDeleted{{Name: "bob"}}, deleted)
+			assert.Equal(t, []string{"user created: alice"}, stringer)
+			assert.Equal(t, []any{userCreated{Name: "alice"}, userDeleted{Name: "bob"}, 42}, all)
+		})
+	}
+}
+
+func TestHandle_Unsubscribe(t *testing.T) {
+	bus := NewBus(NewSyncTopic[any]())
+	defer bus.Close()
+
+	var calls int
+	sub, err := Handle(bus, func(userCreated) {
+		calls++
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(userCreated{}))
+	sub.Unsubscribe()
+	require.NoError(t, bus.Publish(userCreated{}))
+
+	assert.Equal(t, 1, calls)
+}
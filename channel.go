@@ -0,0 +1,74 @@
+package gubgub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Chan subscribes to topic and forwards every message to the returned channel which can hold up to
+// bufSize messages. The channel is closed once the subscriber is removed from the topic, either
+// because the topic is closed or because cancel is called. Just like any other slow subscriber, a
+// channel that is not drained holds back delivery once its buffer is full.
+func Chan[T any](topic HandleSubscribable[T], bufSize int) (<-chan T, func(), error) {
+	ch := make(chan T, max(bufSize, 0))
+	cancelled := make(chan struct{})
+
+	sub, err := topic.SubscribeWithHandle(func(msg T) bool {
+		select {
+		case ch <- msg:
+			return true
+		case <-cancelled:
+			return false
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("chan subscribe: %w", err)
+	}
+
+	// Once the subscriber is removed it is never called again thus the channel can be closed.
+	go func() {
+		<-sub.Done()
+		close(ch)
+	}()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(cancelled) // unblocks the subscriber if it is waiting for room in the channel
+			sub.Unsubscribe()
+		})
+	}
+
+	return ch, cancel, nil
+}
+
+// Pipe publishes every message received from ch to topic until ch is closed, in which case it
+// returns nil. It returns ctx.Err() as soon as ctx is done. If publishing fails, for example with
+// ErrTopicClosed because the topic was closed, Pipe stops and returns that error. The message that
+// failed to be published is lost but any message left in ch is not consumed. If topic implements
+// ContextPublishable then ctx is used for publishing too.
+func Pipe[T any](ctx context.Context, ch <-chan T, topic Publishable[T]) error {
+	publish := topic.Publish
+	if p, ok := topic.(ContextPublishable[T]); ok {
+		publish = func(msg T) error {
+			return p.PublishContext(ctx, msg)
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := publish(msg); err != nil {
+				return fmt.Errorf("pipe publish: %w", err)
+			}
+
+		case <-ctx.Done():
+			return fmt.Errorf("pipe: %w", ctx.Err())
+		}
+	}
+}
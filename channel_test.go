@@ -0,0 +1,114 @@
+package gubgub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChan(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			topic := tc.newTopic()
+
+			ch, cancel, err := Chan(topic, 3)
+			require.NoError(t, err)
+			defer cancel()
+
+			for i := range 3 {
+				require.NoError(t, topic.Publish(i))
+			}
+
+			topic.Close()
+
+			var received []int
+			for msg := range ch { // closed once the topic is closed
+				received = append(received, msg)
+			}
+
+			assert.Equal(t, []int{0, 1, 2}, received)
+		})
+	}
+}
+
+func TestChan_Cancel(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	ch, cancel, err := Chan(topic, 0)
+	require.NoError(t, err)
+
+	published := make(chan error)
+	go func() {
+		published <- topic.Publish(1) // nobody reads from the channel
+	}()
+
+	cancel()
+	cancel() // idempotent
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case err := <-published:
+		assert.NoError(t, err)
+	case <-timeout.C:
+		t.Fatalf("expected cancel to unblock publishing")
+	}
+
+	for range ch { // drain until closed
+	}
+}
+
+func TestChan_TopicClosed(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	topic.Close()
+
+	_, _, err := Chan(topic, 1)
+	assert.ErrorIs(t, err, ErrTopicClosed)
+}
+
+func TestPipe(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	var received []int
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		received = append(received, i)
+	})))
+
+	ch := make(chan int, 3)
+	for i := range 3 {
+		ch <- i
+	}
+	close(ch)
+
+	assert.NoError(t, Pipe(context.Background(), ch, topic))
+	assert.Equal(t, []int{0, 1, 2}, received)
+}
+
+func TestPipe_TopicClosed(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	topic.Close()
+
+	ch := make(chan int, 2)
+	ch <- 1
+	ch <- 2
+
+	err := Pipe(context.Background(), ch, topic)
+	assert.ErrorIs(t, err, ErrTopicClosed)
+	assert.Len(t, ch, 1, "expected remaining messages not to be consumed")
+}
+
+func TestPipe_ContextDone(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Pipe(ctx, make(chan int), topic)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,24 +1,91 @@
 package gubgub
 
+import (
+	"context"
+	"reflect"
+)
+
 // Subscriber is a func that processes a message and returns true if it should continue processing more messages.
 type Subscriber[T any] func(T) bool
 
+// ErrorSubscriber is a func that processes a message and returns an error if processing failed.
+// Failures are reported to the topic's WithOnError handler and the subscriber keeps receiving
+// messages. Return ErrUnsubscribe to stop processing more messages.
+type ErrorSubscriber[T any] func(T) error
+
+// ClosableSubscriber is a subscriber with a lifecycle. Receive processes messages just like a
+// Subscriber func. Close is called once the subscriber is removed from the topic so that it can
+// flush pending work and stop its go routines. When the subscriber is removed because the topic is
+// closed, closing the topic waits for Close to return.
+type ClosableSubscriber[T any] interface {
+	Receive(msg T) bool
+	Closer
+}
+
 // Topic is just a convenience interface you can expect all topics to implement.
 type Topic[T any] interface {
 	Publishable[T]
+	ContextPublishable[T]
 	Subscribable[T]
+	ContextSubscribable[T]
+	HandleSubscribable[T]
+	ErrorSubscribable[T]
+	ClosableSubscribable[T]
 	OptionsSetter
 	Closer
+	Shutdowner
 }
 
 type Publishable[T any] interface {
 	Publish(msg T) error
 }
 
+// ContextPublishable is implemented by topics where publishing can be abandoned with a context.
+type ContextPublishable[T any] interface {
+	PublishContext(ctx context.Context, msg T) error
+}
+
 type Subscribable[T any] interface {
 	Subscribe(Subscriber[T]) error
 }
 
+// ContextSubscribable is implemented by topics where subscribers can be bound to a context. The
+// subscriber is removed once the context is done.
+type ContextSubscribable[T any] interface {
+	SubscribeContext(ctx context.Context, fn Subscriber[T]) error
+}
+
+// HandleSubscribable is implemented by topics that return a Subscription handle which can be used to
+// remove the subscriber at any time.
+type HandleSubscribable[T any] interface {
+	SubscribeWithHandle(Subscriber[T]) (Subscription, error)
+}
+
+// ErrorSubscribable is implemented by topics that accept subscribers returning errors.
+type ErrorSubscribable[T any] interface {
+	SubscribeErr(ErrorSubscriber[T]) (Subscription, error)
+}
+
+// ClosableSubscribable is implemented by topics that close subscribers with a lifecycle once they
+// are removed.
+type ClosableSubscribable[T any] interface {
+	SubscribeClosable(ClosableSubscriber[T]) (Subscription, error)
+}
+
+// GroupSubscribable is implemented by topics that support consumer groups. Each message is delivered
+// to only one member of each group while regular subscribers still get every message.
+type GroupSubscribable[T any] interface {
+	SubscribeGroup(name string, fn Subscriber[T]) (Subscription, error)
+}
+
+// TypeSubscribable is implemented by topics that keep an index of subscribers by message type so that
+// subscribers are only called for messages of the type they are interested in. See SubscribeAs.
+type TypeSubscribable[T any] interface {
+	// SubscribeType subscribes fn to messages whose dynamic type is typ or, if typ is an interface,
+	// implements typ.
+	SubscribeType(typ reflect.Type, fn Subscriber[T]) (Subscription, error)
+}
+
 type OptionsSetter interface {
 	SetOptions(...TopicOption)
 }
@@ -26,3 +93,8 @@ type OptionsSetter interface {
 type Closer interface {
 	Close()
 }
+
+// Shutdowner is implemented by topics that can be closed gracefully within a deadline.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
@@ -0,0 +1,67 @@
+package gubgub
+
+import (
+	"errors"
+	"time"
+)
+
+// DeadLetter describes a message that a subscriber failed to process. It is published to the topic
+// set with WithDeadLetter so that failed messages can be inspected and replayed.
+type DeadLetter[T any] struct {
+	// Message is the message the subscriber failed to process.
+	Message T
+
+	// Err is the error returned by the subscriber. Nil if the subscriber panicked.
+	Err error
+
+	// Recovered is the value recovered from the subscriber panic. Nil if the subscriber returned
+	// an error.
+	Recovered any
+
+	// Attempts is how many times the subscriber tried to process the message. This is greater than
+	// one only if the error reports it, like the RetryError does.
+	Attempts int
+
+	// Time is when the subscriber gave up on the message.
+	Time time.Time
+}
+
+// WithDeadLetter publishes a DeadLetter to the given topic whenever a subscriber fails to process a
+// message, either because an ErrorSubscriber returned an error or because a subscriber panicked.
+// The message type T must match the type of the topic this option is applied to, otherwise failed
+// messages are not published. Errors publishing dead letters are ignored.
+func WithDeadLetter[T any](topic Publishable[DeadLetter[T]]) TopicOption {
+	deadLetter := func(msg any, err error, recovered any) {
+		m, ok := msg.(T)
+		if !ok {
+			return
+		}
+
+		attempts := 1
+
+		var attemptsErr interface{ Attempts() int }
+		if errors.As(err, &attemptsErr) {
+			attempts = attemptsErr.Attempts()
+		}
+
+		_ = topic.Publish(DeadLetter[T]{
+			Message:   m,
+			Err:       err,
+			Recovered: recovered,
+			Attempts:  attempts,
+			Time:      time.Now(),
+		})
+	}
+
+	return func(opts *TopicOptions) {
+		if opts.deadLetter == nil {
+			opts.deadLetter = deadLetter
+		} else {
+			oldFn := opts.deadLetter // preserve previous dead letter handler
+			opts.deadLetter = func(msg any, err error, recovered any) {
+				deadLetter(msg, err, recovered)
+				oldFn(msg, err, recovered)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package gubgub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeadLetter(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	testCases := []struct {
+		name         string
+		subscribe    func(Topic[int]) error
+		expErr       error
+		expRecovered any
+	}{
+		{
+			name: "subscriber returns error",
+			subscribe: func(topic Topic[int]) error {
+				_, err := topic.SubscribeErr(func(int) error { return errFailed })
+				return err
+			},
+			expErr: errFailed,
+		},
+		{
+			name: "subscriber panics",
+			subscribe: func(topic Topic[int]) error {
+				return topic.Subscribe(func(int) bool { panic("boom") })
+			},
+			expRecovered: "boom",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deadLetters := NewSyncTopic[DeadLetter[int]]()
+			t.Cleanup(deadLetters.Close)
+
+			var received []DeadLetter[int]
+			require.NoError(t, deadLetters.Subscribe(Forever(func(dl DeadLetter[int]) {
+				received = append(received, dl)
+			})))
+
+			topic := NewSyncTopic[int](WithDeadLetter(deadLetters))
+			t.Cleanup(topic.Close)
+
+			require.NoError(t, tc.subscribe(topic))
+
+			before := time.Now()
+			require.NoError(t, topic.Publish(42))
+
+			require.Len(t, received, 1)
+			assert.Equal(t, 42, received[0].Message)
+			assert.Equal(t, tc.expErr, received[0].Err)
+			assert.Equal(t, tc.expRecovered, received[0].Recovered)
+			assert.Equal(t, 1, received[0].Attempts)
+			assert.False(t, received[0].Time.Before(before))
+		})
+	}
+}
+
+func TestWithDeadLetter_Unsubscribe(t *testing.T) {
+	deadLetters := NewSyncTopic[DeadLetter[int]]()
+	t.Cleanup(deadLetters.Close)
+
+	var received int
+	require.NoError(t, deadLetters.Subscribe(Forever(func(DeadLetter[int]) {
+		received++
+	})))
+
+	topic := NewSyncTopic[int](WithDeadLetter(deadLetters))
+	t.Cleanup(topic.Close)
+
+	_, err := topic.SubscribeErr(func(int) error { return ErrUnsubscribe })
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+
+	assert.Zero(t, received, "expected unsubscribing not to be considered a failure")
+}
+
+func TestWithDeadLetter_RetryAttempts(t *testing.T) {
+	deadLetters := NewSyncTopic[DeadLetter[int]]()
+	t.Cleanup(deadLetters.Close)
+
+	var received []DeadLetter[int]
+	require.NoError(t, deadLetters.Subscribe(Forever(func(dl DeadLetter[int]) {
+		received = append(received, dl)
+	})))
+
+	topic := NewSyncTopic[int](WithDeadLetter(deadLetters))
+	t.Cleanup(topic.Close)
+
+	_, err := topic.SubscribeErr(Retry(func(int) error {
+		return errors.New("failed")
+	}, WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond)))
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+
+	require.Len(t, received, 1)
+	assert.Equal(t, 3, received[0].Attempts)
+}
@@ -1,27 +1,214 @@
 package gubgub
 
-// sequentialDelivery effentiently delivers a message to each subscriber sequentially. For
-// performance reasons this might mutate the subscribers slice inplace. Please overwrite it with
-// the result of this call.
-func sequentialDelivery[T any](msg T, subscribers []Subscriber[T]) []Subscriber[T] {
-	last := len(subscribers) - 1
-	next := 0
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// sequentialDelivery effentiently delivers a message to each subscriber sequentially. Subscribers
+// that return false are removed while preserving the order of the remaining ones. For performance
+// reasons this mutates the subscribers slice inplace. Please overwrite it with the result of this
+// call.
+func sequentialDelivery[T any](msg T, subscribers []*subscription[T]) []*subscription[T] {
+	d := sequentialDeliverer[T]{subscribers: subscribers}
+
+	for !d.deliver(msg) {
+		// A subscriber panicked, resume with the next one.
+	}
+
+	clear(subscribers[d.kept:]) // allow removed subscribers to be garbage collected
+
+	return subscribers[:d.kept]
+}
+
+// sequentialDeliverer holds the progress of sequentialDelivery so that delivery can resume after a
+// subscriber panics.
+type sequentialDeliverer[T any] struct {
+	subscribers []*subscription[T]
+	next        int // index of the subscriber being delivered to
+	kept        int // subscribers kept so far, these are moved to the front of the slice
+}
+
+// deliver delivers msg to the remaining subscribers. Panics are recovered once for the whole loop
+// rather than once per subscriber, which keeps the common path cheap. Returns false if a subscriber
+// panicked, in which case deliver must be called again to resume delivery.
+func (d *sequentialDeliverer[T]) deliver(msg T) (done bool) {
+	at := d.next // index of the subscriber being delivered to, only read if it panics
+
+	defer func() {
+		if r := recover(); r != nil {
+			d.next, d.kept = at, d.keptBefore(at)
+
+			s := d.subscribers[at]
+			d.keep(s, s.recovered(r, msg))
+			d.next++
+		}
+	}()
+
+	subscribers, next, kept := d.subscribers, d.next, d.kept
+
+	for i, s := range subscribers[next:] {
+		at = next + i
+
+		if !s.deliver(msg) {
+			s.release()
+			continue
+		}
+
+		if kept != at {
+			subscribers[kept] = s
+			s.index = kept
+		}
+
+		kept++
+	}
+
+	d.next, d.kept = len(subscribers), kept
+
+	return true
+}
+
+// keptBefore returns how many subscribers were kept before the one at index i. Kept subscribers are
+// moved to the front of the slice and know their position while the ones after them were either
+// released or moved, thus their index doesn't match their position.
+func (d *sequentialDeliverer[T]) keptBefore(i int) int {
+	for j, s := range d.subscribers[d.kept:i] {
+		if s.index != d.kept+j {
+			return d.kept + j
+		}
+	}
+
+	return i
+}
+
+// keep moves s next to the subscribers kept so far or releases it.
+func (d *sequentialDeliverer[T]) keep(s *subscription[T], keep bool) {
+	if !keep {
+		s.release()
+		return
+	}
+
+	if d.kept != d.next {
+		d.subscribers[d.kept] = s
+		s.index = d.kept
+	}
 
-	for next <= last {
-		if !subscribers[next](msg) {
-			for last > next && !subscribers[last](msg) {
-				last--
+	d.kept++
+}
+
+// DeliveryStrategy decides how a single message is handed to the subscribers of a topic.
+type DeliveryStrategy interface {
+	// Deliver must call deliver exactly once for each subscriber index in the range [0, n) and
+	// return only after all of those calls have returned. Calls for different indexes may run
+	// concurrently.
+	Deliver(n int, deliver func(i int))
+}
+
+// SequentialDelivery returns the default DeliveryStrategy: each subscriber gets the message one
+// after the other, in the same go routine.
+func SequentialDelivery() DeliveryStrategy {
+	return sequentialStrategy{}
+}
+
+type sequentialStrategy struct{}
+
+func (sequentialStrategy) Deliver(n int, deliver func(i int)) {
+	for i := range n {
+		deliver(i)
+	}
+}
+
+// ParallelDelivery returns a DeliveryStrategy that fans each message out to the subscribers using
+// up to workers go routines. This is useful when some subscribers are slow and shouldn't delay
+// every other subscriber. A message is still only considered delivered when all subscribers have
+// returned.
+// Subscribers of the same topic may be called concurrently, although each subscriber is never
+// called concurrently with itself.
+func ParallelDelivery(workers int) DeliveryStrategy {
+	return parallelStrategy{workers: max(workers, 1)}
+}
+
+// parallelChunksPerWorker is roughly how many chunks of subscribers each worker processes per message.
+const parallelChunksPerWorker = 8
+
+type parallelStrategy struct {
+	workers int
+}
+
+func (s parallelStrategy) Deliver(n int, deliver func(i int)) {
+	workers := min(s.workers, n)
+	if workers <= 1 {
+		sequentialStrategy{}.Deliver(n, deliver)
+		return
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+
+	// Workers claim small chunks of subscribers at a time to reduce contention on the counter while
+	// still balancing the load when some subscribers are slower than others.
+	chunk := max(n/(workers*parallelChunksPerWorker), 1)
+
+	work := func() {
+		for {
+			end := int(next.Add(int64(chunk)))
+			start := end - chunk
+			if start >= n {
+				return
 			}
 
-			if last <= next {
-				break
+			for i := start; i < min(end, n); i++ {
+				deliver(i)
 			}
+		}
+	}
+
+	wg.Add(workers - 1)
+	for range workers - 1 {
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+
+	work() // the calling go routine is a worker too
+
+	wg.Wait()
+}
+
+// strategyDelivery delivers a message to each subscriber using the given strategy. Subscribers that
+// return false are removed while preserving the order of the remaining ones. Like
+// sequentialDelivery this might mutate the subscribers slice inplace so please overwrite it with the
+// result of this call.
+func strategyDelivery[T any](strategy DeliveryStrategy, msg T, subscribers []*subscription[T]) []*subscription[T] {
+	switch strategy.(type) {
+	case nil, sequentialStrategy:
+		return sequentialDelivery(msg, subscribers)
+	}
+
+	// Subscribers are only removed if they returned false so that a strategy skipping an index by
+	// mistake doesn't unsubscribe anyone.
+	stopped := make([]bool, len(subscribers))
 
-			subscribers[next] = subscribers[last]
-			last--
+	strategy.Deliver(len(subscribers), func(i int) {
+		stopped[i] = !subscribers[i].safeDeliver(msg)
+	})
+
+	next := 0
+	for i, stop := range stopped {
+		if stop {
+			subscribers[i].release()
+			continue
 		}
+
+		subscribers[next] = subscribers[i]
+		subscribers[next].index = next
 		next++
 	}
 
+	clear(subscribers[next:]) // allow removed subscribers to be garbage collected
+
 	return subscribers[:next]
 }
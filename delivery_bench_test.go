@@ -1,14 +1,17 @@
 package gubgub
 
-import "testing"
+import (
+	"runtime"
+	"testing"
+)
 
 func BenchmarkSequentialDelivery(b *testing.B) {
 	for _, tc := range deliveryCases {
 		b.Run(tc.Name, func(b *testing.B) {
-			subscribers := make([]Subscriber[int], 0, tc.Count)
+			subscribers := make([]*subscription[int], 0, tc.Count)
 
 			for range tc.Count {
-				subscribers = append(subscribers, tc.Subscriber)
+				subscribers = addSubscription(subscribers, newTestSubscription(tc.Subscriber))
 			}
 
 			b.ResetTimer()
@@ -20,7 +23,34 @@ func BenchmarkSequentialDelivery(b *testing.B) {
 
 				// replenish subscribers
 				for len(subscribers) < tc.Count {
-					subscribers = append(subscribers, tc.Subscriber)
+					subscribers = addSubscription(subscribers, newTestSubscription(tc.Subscriber))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParallelDelivery(b *testing.B) {
+	strategy := ParallelDelivery(runtime.GOMAXPROCS(0))
+
+	for _, tc := range deliveryCases {
+		b.Run(tc.Name, func(b *testing.B) {
+			subscribers := make([]*subscription[int], 0, tc.Count)
+
+			for range tc.Count {
+				subscribers = addSubscription(subscribers, newTestSubscription(tc.Subscriber))
+			}
+
+			b.ResetTimer()
+
+			for i := range b.N {
+				b.StartTimer()
+				subscribers = strategyDelivery(strategy, i, subscribers)
+				b.StopTimer()
+
+				// replenish subscribers
+				for len(subscribers) < tc.Count {
+					subscribers = addSubscription(subscribers, newTestSubscription(tc.Subscriber))
 				}
 			}
 		})
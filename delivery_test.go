@@ -1,9 +1,13 @@
 package gubgub
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSequentialDelivery(t *testing.T) {
@@ -11,7 +15,7 @@ func TestSequentialDelivery(t *testing.T) {
 
 	feedback := make([]int, 0, 3)
 
-	subscribers := []Subscriber[int]{
+	subscribers := subscriptionsOf(
 		Once(func(msg int) {
 			assert.Equalf(t, testMsg, msg, "expected %d but got %d", testMsg, msg)
 			feedback = append(feedback, 1)
@@ -28,7 +32,7 @@ func TestSequentialDelivery(t *testing.T) {
 			assert.Equalf(t, testMsg, msg, "expected %d but got %d", testMsg, msg)
 			feedback = append(feedback, 4)
 		}),
-	}
+	)
 
 	nextSubscribers := sequentialDelivery(testMsg, subscribers)
 
@@ -46,6 +50,204 @@ func TestSequentialDelivery(t *testing.T) {
 	assertContainsExactlyN(t, 4, 2, feedback)
 }
 
+func TestSequentialDelivery_Panic(t *testing.T) {
+	var feedback []int
+
+	subscribers := subscriptionsOf(
+		Forever(func(int) { feedback = append(feedback, 1) }),
+		func(int) bool { panic("boom") },
+		Once(func(int) { feedback = append(feedback, 3) }),
+		func(int) bool { panic("boom") },
+		Forever(func(int) { feedback = append(feedback, 5) }),
+	)
+
+	subscribers = sequentialDelivery(1, subscribers)
+
+	assert.Equal(t, []int{1, 3, 5}, feedback, "expected delivery to resume after each panic")
+	require.Len(t, subscribers, 2, "expected panicking subscribers to be dropped")
+
+	for i, s := range subscribers {
+		assert.Equal(t, i, s.index)
+	}
+
+	feedback = nil
+	subscribers = sequentialDelivery(2, subscribers)
+
+	assert.Equal(t, []int{1, 5}, feedback, "expected the order of the remaining subscribers to be kept")
+	assert.Len(t, subscribers, 2)
+}
+
+func TestSequentialDelivery_PanicKeep(t *testing.T) {
+	var feedback []int
+
+	options := &TopicOptions{}
+	options.Apply(WithPanicPolicy(PanicKeep))
+
+	var subscribers []*subscription[int]
+	for _, fn := range []Subscriber[int]{
+		Once(func(int) { feedback = append(feedback, 1) }),
+		Forever(func(int) { feedback = append(feedback, 2) }),
+		Once(func(int) { feedback = append(feedback, 3) }),
+		func(int) bool { feedback = append(feedback, 4); panic("boom") },
+		Forever(func(int) { feedback = append(feedback, 5) }),
+	} {
+		subscribers = addSubscription(subscribers, newSubscription(fn, options, func(*subscription[int]) {}))
+	}
+
+	subscribers = sequentialDelivery(1, subscribers)
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, feedback)
+	require.Len(t, subscribers, 3, "expected the panicking subscriber to be kept")
+
+	for i, s := range subscribers {
+		assert.Equal(t, i, s.index)
+	}
+
+	feedback = nil
+	subscribers = sequentialDelivery(2, subscribers)
+
+	assert.Equal(t, []int{2, 4, 5}, feedback, "expected the order of the remaining subscribers to be kept")
+	assert.Len(t, subscribers, 3)
+}
+
+func TestStrategyDelivery(t *testing.T) {
+	const testMsg = 9786
+
+	testCases := []struct {
+		name     string
+		strategy DeliveryStrategy
+	}{
+		{
+			name:     "sequential",
+			strategy: SequentialDelivery(),
+		},
+		{
+			name:     "parallel",
+			strategy: ParallelDelivery(4),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			feedback := make([]int, 0, 10)
+
+			subscribers := make([]*subscription[int], 0, 10)
+			for i := range 10 {
+				subscribers = addSubscription(subscribers, newTestSubscription(func(msg int) bool {
+					assert.Equalf(t, testMsg, msg, "expected %d but got %d", testMsg, msg)
+
+					mu.Lock()
+					feedback = append(feedback, i)
+					mu.Unlock()
+
+					return i%2 == 0 // odd subscribers unsubscribe
+				}))
+			}
+
+			nextSubscribers := strategyDelivery(tc.strategy, testMsg, subscribers)
+
+			assert.Len(t, nextSubscribers, 5, "expected to have 5 subscribers")
+			assert.Len(t, feedback, 10, "one or more subscriber was not called")
+
+			feedback = feedback[:0]
+			finalSubscribers := strategyDelivery(tc.strategy, testMsg, nextSubscribers)
+
+			assert.Len(t, finalSubscribers, 5, "expected to have the same subscribers")
+			assert.ElementsMatch(t, []int{0, 2, 4, 6, 8}, feedback)
+		})
+	}
+}
+
+// skipFirstStrategy is a DeliveryStrategy that breaks the contract by never calling the first
+// subscriber.
+type skipFirstStrategy struct{}
+
+func (skipFirstStrategy) Deliver(n int, deliver func(i int)) {
+	for i := 1; i < n; i++ {
+		deliver(i)
+	}
+}
+
+func TestStrategyDelivery_SkippedIndex(t *testing.T) {
+	var calls []int
+
+	subscribers := make([]*subscription[int], 0, 3)
+	for i := range 3 {
+		subscribers = addSubscription(subscribers, newTestSubscription(Forever(func(int) {
+			calls = append(calls, i)
+		})))
+	}
+
+	subscribers = strategyDelivery(skipFirstStrategy{}, 1, subscribers)
+
+	assert.Equal(t, []int{1, 2}, calls)
+	assert.Len(t, subscribers, 3, "expected a skipped subscriber to be kept")
+}
+
+func TestParallelDelivery_Concurrent(t *testing.T) {
+	const workers = 4
+
+	var (
+		running atomic.Int64
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ParallelDelivery(workers).Deliver(workers, func(i int) {
+			if running.Add(1) == workers {
+				close(started)
+			}
+			<-release
+		})
+	}()
+
+	select {
+	case <-started:
+		close(release)
+
+	case <-testTimer(t, time.Second).C:
+		t.Fatalf("expected %d concurrent deliveries but only got %d", workers, running.Load())
+	}
+
+	select {
+	case <-done:
+	case <-testTimer(t, time.Second).C:
+		t.Fatalf("expected delivery to return by now")
+	}
+}
+
+func TestParallelDelivery_AllCalledOnce(t *testing.T) {
+	const n = 1000
+
+	calls := make([]atomic.Int64, n)
+
+	ParallelDelivery(8).Deliver(n, func(i int) {
+		calls[i].Add(1)
+	})
+
+	for i := range calls {
+		require.Equalf(t, int64(1), calls[i].Load(), "expected subscriber %d to be called exactly once", i)
+	}
+}
+
+// newTestSubscription creates a subscription that is not owned by any topic.
+func newTestSubscription[T any](fn Subscriber[T]) *subscription[T] {
+	return newSubscription(fn, &TopicOptions{}, func(*subscription[T]) {})
+}
+
+func subscriptionsOf[T any](fns ...Subscriber[T]) []*subscription[T] {
+	subscribers := make([]*subscription[T], 0, len(fns))
+	for _, fn := range fns {
+		subscribers = addSubscription(subscribers, newTestSubscription(fn))
+	}
+
+	return subscribers
+}
+
 func assertContainsExactlyN[T comparable](t testing.TB, exp T, n int, slice []T) {
 	t.Helper()
 
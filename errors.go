@@ -3,3 +3,61 @@ package gubgub
 import "fmt"
 
 var ErrTopicClosed = fmt.Errorf("topic is closed")
+
+// ErrTopicFull is returned when publishing to a topic whose queue is full and the topic was
+// configured with the OverflowReturnError policy.
+var ErrTopicFull = fmt.Errorf("topic is full")
+
+// ErrUnsubscribe can be returned by an ErrorSubscriber to stop receiving messages. Errors wrapping
+// ErrUnsubscribe also unsubscribe but are reported as failures too.
+var ErrUnsubscribe = fmt.Errorf("unsubscribe")
+
+// ErrTopicType is returned when a topic is looked up in a Broker with a TopicKey whose message type
+// doesn't match the type the topic was created with.
+var ErrTopicType = fmt.Errorf("topic message type mismatch")
+
+// ErrRejected is reported to the dead letter topic, wrapped in a RejectedError, when an
+// AckSubscriber rejects a message without requeueing it.
+var ErrRejected = fmt.Errorf("message rejected")
+
+// ErrRequesterClosed is returned by a Requester that was closed, including to requests waiting for a
+// response.
+var ErrRequesterClosed = fmt.Errorf("requester is closed")
+
+// UndeliveredError is returned by Shutdown when the topic could not deliver every published message
+// before the context was done.
+type UndeliveredError struct {
+	// Undelivered is how many published messages were not delivered to every subscriber, either
+	// because their delivery was in progress or because it never started.
+	Undelivered int
+
+	// Err is the reason why Shutdown stopped waiting. Usually the context error.
+	Err error
+}
+
+func (e *UndeliveredError) Error() string {
+	return fmt.Sprintf("%d messages left undelivered: %v", e.Undelivered, e.Err)
+}
+
+func (e *UndeliveredError) Unwrap() error {
+	return e.Err
+}
+
+// RejectedError is reported to the dead letter topic when an AckSubscriber rejects a message without
+// requeueing it. It wraps ErrRejected.
+type RejectedError struct {
+	attempts int
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%v after %d attempts", ErrRejected, e.attempts)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return ErrRejected
+}
+
+// Attempts returns how many times the message was delivered before it was rejected.
+func (e *RejectedError) Attempts() int {
+	return e.attempts
+}
@@ -2,15 +2,13 @@ package gubgub_test
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/nmoniz/gubgub"
 )
 
 func ExampleAsyncTopic() {
-	latch := make(chan struct{})
-	topic := gubgub.NewAsyncTopic[string](gubgub.WithOnSubscribe(func() { close(latch) }))
+	topic := gubgub.NewAsyncTopic[string]()
 	defer topic.Close() // It's ok to close a topic multiple times
 
 	receiver := make(chan string, 3) // closed later
@@ -19,7 +17,7 @@ func ExampleAsyncTopic() {
 		receiver <- strings.ToUpper(msg)
 	}))
 
-	<-latch // Wait for the subscribe to register
+	// No need to wait for the subscriber to be registered: it gets every message published from now on.
 
 	_ = topic.Publish("aaa")
 	_ = topic.Publish("bbb")
@@ -29,15 +27,8 @@ func ExampleAsyncTopic() {
 
 	close(receiver) // Now it's safe to close the receiver channel since no more messages will be delivered
 
-	msgLst := make([]string, 0, 3)
 	for msg := range receiver {
-		msgLst = append(msgLst, msg)
-	}
-
-	sort.Strings(msgLst) // Because publish order is not guaranteed with the AsyncTopic (yet) we need to sort results for consistent output
-
-	for _, msg := range msgLst {
-		fmt.Println(msg)
+		fmt.Println(msg) // Messages are delivered in the same order they were published
 	}
 
 	// Output: AAA
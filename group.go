@@ -0,0 +1,164 @@
+package gubgub
+
+import "sync"
+
+// consumerGroups keeps track of the consumer groups of a topic. The zero value is ready to use.
+type consumerGroups[T any] struct {
+	mu     sync.Mutex
+	groups map[string]*consumerGroup[T]
+}
+
+// join adds fn as a member of the named group. The group is created and registered in the topic
+// with subscribe if it doesn't exist yet. The group unsubscribes from the topic with unsubscribe
+// once its last member leaves.
+func (gs *consumerGroups[T]) join(
+	name string,
+	fn Subscriber[T],
+	options *TopicOptions,
+	subscribe func(*subscription[T]) error,
+	unsubscribe func(*subscription[T]),
+) (Subscription, error) {
+	gs.mu.Lock()
+
+	g := gs.groups[name]
+	m := newSubscription(fn, options, func(m *subscription[T]) { g.leave(m) })
+
+	// A group whose members all left is about to be removed so a new one takes its place.
+	created := g == nil || !g.add(m)
+	if created {
+		g = newConsumerGroup(options, unsubscribe)
+		g.forget = func() { gs.forget(name, g) }
+		g.add(m)
+
+		if gs.groups == nil {
+			gs.groups = make(map[string]*consumerGroup[T])
+		}
+		gs.groups[name] = g
+	}
+
+	gs.mu.Unlock()
+
+	// Subscribing might have to wait for the topic lock so it must not happen while holding the
+	// groups lock, otherwise a topic closing its groups could deadlock.
+	if created {
+		if err := subscribe(g.sub); err != nil {
+			g.Close()
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// forget removes g from the groups unless it was already replaced by a new group with the same name.
+func (gs *consumerGroups[T]) forget(name string, g *consumerGroup[T]) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.groups[name] == g {
+		delete(gs.groups, name)
+	}
+}
+
+// consumerGroup is subscribed to the topic like any other subscriber but hands each message to only
+// one of its members, in turns (round-robin).
+type consumerGroup[T any] struct {
+	sub    *subscription[T] // the subscription of the group itself in the topic
+	forget func()           // removes the group from the topic groups
+
+	mu      sync.Mutex
+	members []*subscription[T]
+	next    int
+	closed  bool // true once the group has no members left or the topic removed it
+
+	delivering sync.Mutex // held while a message is delivered to a member
+}
+
+func newConsumerGroup[T any](options *TopicOptions, unsubscribe func(*subscription[T])) *consumerGroup[T] {
+	g := &consumerGroup[T]{}
+	g.sub = newClosableSubscription[T](g, options, unsubscribe)
+
+	return g
+}
+
+// Receive delivers msg to the next member. A member that returns false leaves the group.
+func (g *consumerGroup[T]) Receive(msg T) bool {
+	m, ok := g.pick()
+	if !ok {
+		return false
+	}
+
+	g.delivering.Lock()
+	keep := m.safeDeliver(msg)
+	g.delivering.Unlock()
+
+	if !keep {
+		m.Unsubscribe()
+	}
+
+	return true
+}
+
+// Close removes all members. This is called once the group is removed from the topic.
+func (g *consumerGroup[T]) Close() {
+	g.mu.Lock()
+	g.closed = true
+	g.members = releaseSubscriptions(g.members)
+	g.mu.Unlock()
+
+	g.forget()
+}
+
+// pick returns the member whose turn it is to get a message, skipping members that left in the
+// meantime. Returns false if there are no members.
+func (g *consumerGroup[T]) pick() (*subscription[T], bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for range g.members {
+		m := g.members[g.next%len(g.members)]
+		g.next++
+
+		if !m.unsubscribed.Load() {
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+// add makes m a member of the group. Returns false if the group is closed.
+func (g *consumerGroup[T]) add(m *subscription[T]) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return false
+	}
+
+	g.members = addSubscription(g.members, m)
+
+	return true
+}
+
+// leave removes m from the group. Just like for subscribers of a topic, m is only removed once the
+// message being delivered, if any, is done. The group unsubscribes from the topic once the last
+// member leaves.
+func (g *consumerGroup[T]) leave(m *subscription[T]) {
+	withLock(&g.delivering, func() {
+		g.mu.Lock()
+
+		g.members = removeSubscription(g.members, m)
+
+		empty := len(g.members) == 0 && !g.closed
+		if empty {
+			g.closed = true
+		}
+
+		g.mu.Unlock()
+
+		if empty {
+			g.sub.Unsubscribe()
+		}
+	})
+}
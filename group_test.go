@@ -0,0 +1,141 @@
+package gubgub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeGroup(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			const msgCount = 30
+
+			topic := tc.newTopic()
+
+			var broadcast int
+			require.NoError(t, topic.Subscribe(Forever(func(int) {
+				broadcast++
+			})))
+
+			workers := make([]int, 3)
+			var subs []Subscription
+			for i := range workers {
+				sub, err := topic.SubscribeGroup("workers", Forever(func(int) {
+					workers[i]++
+				}))
+				require.NoError(t, err)
+				subs = append(subs, sub)
+			}
+
+			var auditor int
+			_, err := topic.SubscribeGroup("auditors", Forever(func(int) {
+				auditor++
+			}))
+			require.NoError(t, err)
+
+			for i := range msgCount {
+				require.NoError(t, topic.Publish(i))
+			}
+
+			topic.Close()
+
+			assert.Equal(t, msgCount, broadcast, "expected regular subscribers to get every message")
+			assert.Equal(t, msgCount, auditor, "expected a lone group member to get every message")
+			assert.Equal(t, []int{10, 10, 10}, workers, "expected messages to be spread evenly")
+
+			for _, sub := range subs {
+				assertClosed(t, sub.Done())
+			}
+		})
+	}
+}
+
+func TestSubscribeGroup_Leave(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	var once, forever []int
+
+	onceSub, err := topic.SubscribeGroup("group", Once(func(i int) {
+		once = append(once, i)
+	}))
+	require.NoError(t, err)
+
+	_, err = topic.SubscribeGroup("group", Forever(func(i int) {
+		forever = append(forever, i)
+	}))
+	require.NoError(t, err)
+
+	for i := range 4 {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	assertClosed(t, onceSub.Done())
+	assert.Equal(t, []int{0}, once)
+	assert.Equal(t, []int{1, 2, 3}, forever, "expected remaining members to get all other messages")
+}
+
+func TestSubscribeGroup_LastMemberLeaves(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	sub, err := topic.SubscribeGroup("group", NoOp[int]())
+	require.NoError(t, err)
+
+	sub.Unsubscribe()
+	assertClosed(t, sub.Done())
+
+	var received []int
+	_, err = topic.SubscribeGroup("group", Forever(func(i int) {
+		received = append(received, i)
+	}))
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+
+	assert.Equal(t, []int{1}, received, "expected the group to be created again")
+}
+
+func TestSubscribeGroup_UnsubscribeWhileDelivering(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	sub, err := topic.SubscribeGroup("group", Forever(func(int) {
+		close(started)
+		<-release
+	}))
+	require.NoError(t, err)
+
+	go func() { _ = topic.Publish(1) }()
+	<-started
+
+	sub.Unsubscribe()
+
+	select {
+	case <-sub.Done():
+		t.Fatalf("expected member to stay subscribed until the delivery returns")
+	default:
+	}
+
+	close(release)
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case <-sub.Done():
+	case <-timeout.C:
+		t.Fatalf("expected member to be removed once the delivery returned")
+	}
+}
+
+func TestSubscribeGroup_TopicClosed(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	topic.Close()
+
+	_, err := topic.SubscribeGroup("group", NoOp[int]())
+	assert.ErrorIs(t, err, ErrTopicClosed)
+}
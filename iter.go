@@ -0,0 +1,122 @@
+package gubgub
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// DefaultIterBufferSize is how many messages All buffers for a slow consumer unless
+// WithIterBuffer says otherwise.
+const DefaultIterBufferSize = 64
+
+// IterOptions holds the options of the All iterator.
+type IterOptions struct {
+	bufferSize int
+	overflow   BufferPolicy
+	onDrop     func(msg any)
+}
+
+// IterOption sets an option of the All iterator.
+type IterOption func(*IterOptions)
+
+// WithIterBuffer sets how many messages are buffered while the loop body is busy and what happens
+// once the buffer is full. Defaults to DefaultIterBufferSize and BufferDropOldest.
+func WithIterBuffer(size int, policy BufferPolicy) IterOption {
+	return func(opts *IterOptions) {
+		opts.bufferSize = max(size, 1)
+		opts.overflow = policy
+	}
+}
+
+// WithIterOnDrop registers a func to be called with each message dropped because the buffer was
+// full. Also called with the messages left in the buffer once the loop breaks or ctx is done.
+func WithIterOnDrop(fn func(msg any)) IterOption {
+	return func(opts *IterOptions) {
+		opts.onDrop = fn
+	}
+}
+
+// All returns an iterator over the messages published to topic once the iteration starts:
+//
+//	for msg := range gubgub.All(ctx, topic) {
+//		...
+//	}
+//
+// The iteration ends once the topic is closed, once ctx is done or when the loop breaks, in which
+// case the iterator unsubscribes. If subscribing fails, for example because the topic is already
+// closed, there are no messages to iterate over.
+//
+// Messages are buffered so that a busy loop body doesn't block publishers. By default the oldest
+// message is dropped once the buffer is full so publishing to a SyncTopic never blocks for long,
+// use WithIterBuffer to change that and WithIterOnDrop to find out about dropped messages.
+func All[T any](ctx context.Context, topic HandleSubscribable[T], opts ...IterOption) iter.Seq[T] {
+	options := IterOptions{
+		bufferSize: DefaultIterBufferSize,
+		overflow:   BufferDropOldest,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var onDrop func(T)
+	if options.onDrop != nil {
+		onDrop = func(msg T) { options.onDrop(msg) }
+	}
+
+	return func(yield func(T) bool) {
+		out := make(chan T)
+		done := make(chan struct{}) // closed once the loop is over
+
+		buffered := NewBufferedN(options.bufferSize, options.overflow, func(msg T) bool {
+			select {
+			case out <- msg:
+				return true
+			case <-done:
+				return false
+			}
+		}, onDrop)
+
+		sub, err := topic.SubscribeWithHandle(buffered.Receive)
+		if err != nil {
+			buffered.Close()
+			return
+		}
+
+		defer sub.Unsubscribe()
+		defer close(done)
+
+		// Once the subscriber is removed, the remaining buffered messages are flushed before the
+		// iteration ends.
+		go func() {
+			<-sub.Done()
+			buffered.Close()
+			close(out)
+		}()
+
+		for {
+			select {
+			case msg, ok := <-out:
+				if !ok || !yield(msg) {
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// PublishSeq publishes every message of seq to topic. It stops at the first message that can't be
+// published and returns that error.
+func PublishSeq[T any](topic Publishable[T], seq iter.Seq[T]) error {
+	for msg := range seq {
+		if err := topic.Publish(msg); err != nil {
+			return fmt.Errorf("publish seq: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package gubgub
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+			topic := tc.newTopic(onSubscribe)
+
+			go func() {
+				<-subscriberReady
+				for i := range 5 {
+					assert.NoError(t, topic.Publish(i))
+				}
+				topic.Close()
+			}()
+
+			var received []int
+			for msg := range All(context.Background(), topic, WithIterBuffer(5, BufferBlock)) {
+				received = append(received, msg)
+			}
+
+			assert.Equal(t, []int{0, 1, 2, 3, 4}, received)
+		})
+	}
+}
+
+func TestAll_Break(t *testing.T) {
+	onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+	topic := NewSyncTopic[int](onSubscribe)
+	defer topic.Close()
+
+	go func() {
+		<-subscriberReady
+		for i := 0; ; i++ {
+			if topic.Publish(i) != nil {
+				return
+			}
+		}
+	}()
+
+	var received []int
+	for msg := range All(context.Background(), topic) {
+		received = append(received, msg)
+		if len(received) == 3 {
+			break
+		}
+	}
+
+	assert.Len(t, received, 3)
+	assert.Eventually(t, func() bool {
+		topic.mu.Lock()
+		defer topic.mu.Unlock()
+		return len(topic.subscribers) == 0
+	}, time.Second, time.Millisecond, "expected the iterator to unsubscribe")
+}
+
+func TestAll_ContextDone(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	for range All(ctx, topic) {
+		t.Fatalf("expected no messages")
+	}
+}
+
+func TestAll_DropOldest(t *testing.T) {
+	onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+	topic := NewSyncTopic[int](onSubscribe)
+
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	received := make(chan []int)
+
+	var (
+		mu      sync.Mutex
+		dropped []int
+	)
+	onDrop := WithIterOnDrop(func(msg any) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped = append(dropped, msg.(int))
+	})
+
+	go func() {
+		var msgs []int
+		for msg := range All(context.Background(), topic, WithIterBuffer(2, BufferDropOldest), onDrop) {
+			if msg == 0 {
+				close(busy)
+				<-release // busy loop body
+			}
+			msgs = append(msgs, msg)
+		}
+		received <- msgs
+	}()
+
+	<-subscriberReady
+	require.NoError(t, topic.Publish(0))
+	<-busy
+
+	for i := 1; i < 10; i++ {
+		require.NoError(t, topic.Publish(i), "expected publishing not to block")
+	}
+
+	close(release)
+	topic.Close()
+
+	msgs := <-received
+
+	// Besides the messages kept in the buffer, one message might have been waiting for the loop
+	// body to be done with the first one.
+	assert.Equal(t, 0, msgs[0])
+	assert.LessOrEqual(t, len(msgs), 4)
+	assert.Equal(t, []int{8, 9}, msgs[len(msgs)-2:], "expected the newest messages to be kept")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, dropped, 10-len(msgs), "expected every dropped message to be reported")
+}
+
+func TestPublishSeq(t *testing.T) {
+	topic := NewSyncTopic[int]()
+
+	var received []int
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		received = append(received, i)
+	})))
+
+	assert.NoError(t, PublishSeq(topic, slices.Values([]int{1, 2, 3})))
+	assert.Equal(t, []int{1, 2, 3}, received)
+
+	topic.Close()
+
+	assert.ErrorIs(t, PublishSeq(topic, slices.Values([]int{4})), ErrTopicClosed)
+}
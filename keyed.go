@@ -0,0 +1,145 @@
+package gubgub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyedTopic broadcasts messages to the subscribers of a given key. Publishing a message only calls
+// the subscribers of its key which makes it well suited for a large number of subscribers that are
+// each interested in a few keys, like one subscriber per session.
+// Just like a SyncTopic, publishing and subscribing happens synchronously (block).
+type KeyedTopic[K comparable, V any] struct {
+	options TopicOptions
+
+	closed      atomic.Bool
+	mu          sync.Mutex
+	subscribers map[K][]*subscription[V]
+}
+
+// NewKeyedTopic creates a KeyedTopic with the specified options.
+func NewKeyedTopic[K comparable, V any](opts ...TopicOption) *KeyedTopic[K, V] {
+	t := &KeyedTopic[K, V]{
+		subscribers: make(map[K][]*subscription[V]),
+	}
+
+	t.SetOptions(opts...)
+
+	return t
+}
+
+// Close will prevent further publishing and subscribing. All subscriptions are removed. If a message
+// is being delivered, for example when closing from within a subscriber, the subscriptions are
+// removed once that delivery is done and this returns right away. Either way the WithOnClose
+// callback is called after subscriptions are removed.
+func (t *KeyedTopic[K, V]) Close() {
+	if t.closed.Swap(true) {
+		return
+	}
+
+	t.release()
+}
+
+// release removes all subscribers once the topic is closed and then calls the close callback. Like
+// withLock, this happens in a new go routine if the lock is not immediately available.
+func (t *KeyedTopic[K, V]) release() {
+	release := func() {
+		for key, subscribers := range t.subscribers {
+			releaseSubscriptions(subscribers)
+			delete(t.subscribers, key)
+		}
+		t.mu.Unlock()
+
+		t.options.TriggerClose()
+	}
+
+	if t.mu.TryLock() {
+		release()
+		return
+	}
+
+	go func() {
+		t.mu.Lock()
+		release()
+	}()
+}
+
+// Publish broadcasts a message to all subscribers of key.
+func (t *KeyedTopic[K, V]) Publish(key K, msg V) error {
+	if t.closed.Load() {
+		return fmt.Errorf("keyed topic publish: %w", ErrTopicClosed)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Checking while holding the lock ensures nothing is delivered after Close released subscribers.
+	if t.closed.Load() {
+		return fmt.Errorf("keyed topic publish: %w", ErrTopicClosed)
+	}
+
+	subscribers, ok := t.subscribers[key]
+	if !ok {
+		return nil
+	}
+
+	t.setSubscribers(key, strategyDelivery(t.options.Delivery(), msg, subscribers))
+
+	return nil
+}
+
+// Subscribe adds a Subscriber func that will consume future messages published for key.
+func (t *KeyedTopic[K, V]) Subscribe(key K, fn Subscriber[V]) error {
+	_, err := t.SubscribeWithHandle(key, fn)
+	return err
+}
+
+// SubscribeWithHandle adds a Subscriber func that will consume future messages published for key and
+// returns a Subscription that can be used to remove it.
+func (t *KeyedTopic[K, V]) SubscribeWithHandle(key K, fn Subscriber[V]) (Subscription, error) {
+	s := newSubscription(fn, &t.options, func(s *subscription[V]) {
+		t.unsubscribe(key, s)
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Checking while holding the lock ensures no subscriber is added after Close released them.
+	if t.closed.Load() {
+		s.detach()
+		return nil, fmt.Errorf("keyed topic subscribe: %w", ErrTopicClosed)
+	}
+
+	t.setSubscribers(key, addSubscription(t.subscribers[key], s))
+	t.options.TriggerSubscribe()
+
+	return s, nil
+}
+
+func (t *KeyedTopic[K, V]) SetOptions(opts ...TopicOption) {
+	t.options.Apply(opts...)
+}
+
+// unsubscribe removes s from the subscribers of key.
+func (t *KeyedTopic[K, V]) unsubscribe(key K, s *subscription[V]) {
+	t.withLock(func() {
+		t.setSubscribers(key, removeSubscription(t.subscribers[key], s))
+	})
+}
+
+// setSubscribers replaces the subscribers of key. Keys without subscribers are removed so that memory
+// usage doesn't grow with every key ever used. The lock must be held.
+func (t *KeyedTopic[K, V]) setSubscribers(key K, subscribers []*subscription[V]) {
+	if len(subscribers) == 0 {
+		delete(t.subscribers, key)
+		return
+	}
+
+	t.subscribers[key] = subscribers
+}
+
+// withLock runs fn while holding the lock, see withLock.
+func (t *KeyedTopic[K, V]) withLock(fn func()) {
+	withLock(&t.mu, fn)
+}
@@ -0,0 +1,27 @@
+package gubgub
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkKeyedTopic(b *testing.B) {
+	for _, keys := range []int{100, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("%d keys", keys), func(b *testing.B) {
+			topic := NewKeyedTopic[int, int]()
+			defer topic.Close()
+
+			for key := range keys {
+				_ = topic.Subscribe(key, NoOp[int]())
+			}
+
+			b.ReportAllocs()
+
+			var key int
+			for b.Loop() {
+				_ = topic.Publish(key, key)
+				key = (key + 1) % keys
+			}
+		})
+	}
+}
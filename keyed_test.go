@@ -0,0 +1,126 @@
+package gubgub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedTopic(t *testing.T) {
+	topic := NewKeyedTopic[string, int]()
+
+	received := make(map[string][]int)
+	for _, key := range []string{"alice", "bob"} {
+		require.NoError(t, topic.Subscribe(key, Forever(func(i int) {
+			received[key] = append(received[key], i)
+		})))
+	}
+
+	require.NoError(t, topic.Publish("alice", 1))
+	require.NoError(t, topic.Publish("bob", 2))
+	require.NoError(t, topic.Publish("alice", 3))
+	require.NoError(t, topic.Publish("carol", 4)) // nobody cares
+
+	assert.Equal(t, map[string][]int{"alice": {1, 3}, "bob": {2}}, received)
+
+	topic.Close()
+
+	assert.ErrorIs(t, topic.Publish("alice", 5), ErrTopicClosed)
+	assert.ErrorIs(t, topic.Subscribe("alice", NoOp[int]()), ErrTopicClosed)
+}
+
+func TestKeyedTopic_Unsubscribe(t *testing.T) {
+	topic := NewKeyedTopic[string, int]()
+	defer topic.Close()
+
+	var calls int
+	require.NoError(t, topic.Subscribe("alice", Once(func(int) {
+		calls++
+	})))
+
+	sub, err := topic.SubscribeWithHandle("bob", NoOp[int]())
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish("alice", 1))
+	require.NoError(t, topic.Publish("alice", 2))
+	assert.Equal(t, 1, calls)
+
+	sub.Unsubscribe()
+	assertClosed(t, sub.Done())
+
+	assert.Empty(t, topic.subscribers, "expected keys without subscribers to be removed")
+}
+
+func TestKeyedTopic_UnsubscribeFromSubscriber(t *testing.T) {
+	topic := NewKeyedTopic[string, int]()
+	defer topic.Close()
+
+	var (
+		sub   Subscription
+		calls int
+	)
+
+	sub, err := topic.SubscribeWithHandle("alice", func(int) bool {
+		calls++
+		sub.Unsubscribe() // must not deadlock
+		return true
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish("alice", 1))
+	<-sub.Done()
+	require.NoError(t, topic.Publish("alice", 2))
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestKeyedTopic_Close(t *testing.T) {
+	var closed bool
+	topic := NewKeyedTopic[string, int](WithOnClose(func() {
+		closed = true
+	}))
+
+	sub, err := topic.SubscribeWithHandle("alice", NoOp[int]())
+	require.NoError(t, err)
+
+	topic.Close()
+	topic.Close() // idempotent
+
+	assert.True(t, closed)
+	assertClosed(t, sub.Done())
+}
+
+func TestKeyedTopic_CloseDuringPublish(t *testing.T) {
+	var sub Subscription
+
+	onCloseOrder := make(chan bool, 1)
+	topic := NewKeyedTopic[string, int](WithOnClose(func() {
+		select {
+		case <-sub.Done():
+			onCloseOrder <- true
+		default:
+			onCloseOrder <- false
+		}
+	}))
+
+	_, err := topic.SubscribeWithHandle("alice", Once(func(int) {
+		topic.Close() // the subscriptions are removed once this delivery is done
+	}))
+	require.NoError(t, err)
+
+	sub, err = topic.SubscribeWithHandle("alice", NoOp[int]())
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish("alice", 1))
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case released := <-onCloseOrder:
+		assert.True(t, released, "expected the close callback after subscriptions are removed")
+	case <-timeout.C:
+		t.Fatalf("expected the close callback to be called")
+	}
+}
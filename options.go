@@ -1,6 +1,10 @@
 package gubgub
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // TopicOptions holds common options for topics.
 type TopicOptions struct {
@@ -13,8 +17,85 @@ type TopicOptions struct {
 
 	// onSubscribe is called after a new subscriber is regitered.
 	onSubscribe func()
+
+	// onUndelivered is called with each message that was published but abandoned by Shutdown
+	// before its delivery started.
+	onUndelivered func(msg any)
+
+	// onError is called when an ErrorSubscriber fails with the message, the error and the failing
+	// subscription.
+	onError func(msg any, err error, sub Subscription)
+
+	// onPanic is called when a subscriber panics with the recovered value and the message.
+	onPanic func(recovered any, msg any)
+
+	// deadLetter is called when a subscriber fails to process a message with either the error or
+	// the value recovered from a panic.
+	deadLetter func(msg any, err error, recovered any)
+
+	// panicPolicy decides whether a subscriber that panics remains subscribed.
+	panicPolicy PanicPolicy
+
+	// delivery is how messages are handed to subscribers. Defaults to SequentialDelivery when nil.
+	delivery DeliveryStrategy
+
+	// queueSize is the maximum number of messages waiting to be delivered. Zero means unbounded.
+	queueSize int
+
+	// overflow is what happens when publishing to a full queue.
+	overflow OverflowPolicy
+
+	// replayLatest makes topics retain the last delivered message and deliver it to new subscribers.
+	replayLatest bool
+
+	// visibilityTimeout is how long an AckSubscriber has to settle a delivery before the message is
+	// delivered again. Defaults to DefaultVisibilityTimeout when zero.
+	visibilityTimeout time.Duration
+
+	// publishing mirrors the options topics need for every message so that they can be read
+	// without taking the lock. Updated by Apply.
+	publishing atomic.Pointer[publishingOptions]
+}
+
+// publishingOptions are the options topics read for every message.
+type publishingOptions struct {
+	delivery     DeliveryStrategy
+	replayLatest bool
 }
 
+// DefaultVisibilityTimeout is how long an AckSubscriber has to settle a delivery unless the topic is
+// created with WithVisibilityTimeout.
+const DefaultVisibilityTimeout = 30 * time.Second
+
+// PanicPolicy decides what happens to a subscriber that panics while handling a message.
+type PanicPolicy int
+
+const (
+	// PanicDrop unsubscribes a subscriber that panics. This is the default.
+	PanicDrop PanicPolicy = iota
+
+	// PanicKeep keeps a subscriber that panics subscribed as if it had returned true.
+	PanicKeep
+)
+
+// OverflowPolicy decides what happens when a message is published to a topic whose queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the publisher until there is room in the queue. This is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the message being published.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest queued message to make room for the message being
+	// published.
+	OverflowDropOldest
+
+	// OverflowReturnError rejects the message being published with ErrTopicFull.
+	OverflowReturnError
+)
+
 func (to *TopicOptions) TriggerClose() {
 	to.mu.Lock()
 	defer to.mu.Unlock()
@@ -37,6 +118,104 @@ func (to *TopicOptions) TriggerSubscribe() {
 	to.onSubscribe()
 }
 
+func (to *TopicOptions) TriggerUndelivered(msg any) {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	if to.onUndelivered == nil {
+		return
+	}
+
+	to.onUndelivered(msg)
+}
+
+func (to *TopicOptions) TriggerError(msg any, err error, sub Subscription) {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	if to.onError == nil {
+		return
+	}
+
+	to.onError(msg, err, sub)
+}
+
+func (to *TopicOptions) TriggerPanic(recovered any, msg any) {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	if to.onPanic == nil {
+		return
+	}
+
+	to.onPanic(recovered, msg)
+}
+
+func (to *TopicOptions) TriggerDeadLetter(msg any, err error, recovered any) {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	if to.deadLetter == nil {
+		return
+	}
+
+	to.deadLetter(msg, err, recovered)
+}
+
+// PanicPolicy returns what topics should do with subscribers that panic.
+func (to *TopicOptions) PanicPolicy() PanicPolicy {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	return to.panicPolicy
+}
+
+// Delivery returns the DeliveryStrategy topics should use to deliver messages to subscribers.
+func (to *TopicOptions) Delivery() DeliveryStrategy {
+	if p := to.publishing.Load(); p != nil && p.delivery != nil {
+		return p.delivery
+	}
+
+	return SequentialDelivery()
+}
+
+// QueueSize returns the maximum number of messages a topic should hold waiting for delivery. Zero
+// means there is no limit.
+func (to *TopicOptions) QueueSize() int {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	return to.queueSize
+}
+
+// Overflow returns the policy topics should follow when publishing to a full queue.
+func (to *TopicOptions) Overflow() OverflowPolicy {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	return to.overflow
+}
+
+// ReplayLatest returns whether topics should deliver the last delivered message to new subscribers.
+func (to *TopicOptions) ReplayLatest() bool {
+	p := to.publishing.Load()
+
+	return p != nil && p.replayLatest
+}
+
+// VisibilityTimeout returns how long an AckSubscriber has to settle a delivery before the message is
+// delivered again.
+func (to *TopicOptions) VisibilityTimeout() time.Duration {
+	to.mu.Lock()
+	defer to.mu.Unlock()
+
+	if to.visibilityTimeout <= 0 {
+		return DefaultVisibilityTimeout
+	}
+
+	return to.visibilityTimeout
+}
+
 func (to *TopicOptions) Apply(opts ...TopicOption) {
 	to.mu.Lock()
 	defer to.mu.Unlock()
@@ -44,6 +223,11 @@ func (to *TopicOptions) Apply(opts ...TopicOption) {
 	for _, opt := range opts {
 		opt(to)
 	}
+
+	to.publishing.Store(&publishingOptions{
+		delivery:     to.delivery,
+		replayLatest: to.replayLatest,
+	})
 }
 
 type TopicOption func(*TopicOptions)
@@ -75,3 +259,107 @@ func WithOnSubscribe(fn func()) TopicOption {
 		}
 	}
 }
+
+// WithOnUndelivered registers a func to be called with each message that was successfully published
+// but whose delivery never started because Shutdown gave up waiting for it. This allows those
+// messages to be persisted or handed somewhere else.
+func WithOnUndelivered(fn func(msg any)) TopicOption {
+	return func(opts *TopicOptions) {
+		if opts.onUndelivered == nil {
+			opts.onUndelivered = fn
+		} else {
+			oldFn := opts.onUndelivered // preserve previous onUndelivered handler
+			opts.onUndelivered = func(msg any) {
+				fn(msg)
+				oldFn(msg)
+			}
+		}
+	}
+}
+
+// WithOnError registers a func to be called whenever an ErrorSubscriber fails to process a message.
+// The func gets the message, the error and the Subscription of the failing subscriber which can be
+// used to tell subscribers apart or to unsubscribe them.
+func WithOnError(fn func(msg any, err error, sub Subscription)) TopicOption {
+	return func(opts *TopicOptions) {
+		if opts.onError == nil {
+			opts.onError = fn
+		} else {
+			oldFn := opts.onError // preserve previous onError handler
+			opts.onError = func(msg any, err error, sub Subscription) {
+				fn(msg, err, sub)
+				oldFn(msg, err, sub)
+			}
+		}
+	}
+}
+
+// WithOnPanic registers a func to be called whenever a subscriber panics while handling a message.
+// Topics always recover such panics so that one buggy subscriber can't bring down the publisher or
+// the other subscribers. The func gets the recovered value and the message being delivered.
+func WithOnPanic(fn func(recovered any, msg any)) TopicOption {
+	return func(opts *TopicOptions) {
+		if opts.onPanic == nil {
+			opts.onPanic = fn
+		} else {
+			oldFn := opts.onPanic // preserve previous onPanic handler
+			opts.onPanic = func(recovered any, msg any) {
+				fn(recovered, msg)
+				oldFn(recovered, msg)
+			}
+		}
+	}
+}
+
+// WithPanicPolicy sets whether subscribers that panic are dropped (default) or kept.
+func WithPanicPolicy(policy PanicPolicy) TopicOption {
+	return func(opts *TopicOptions) {
+		opts.panicPolicy = policy
+	}
+}
+
+// WithDelivery sets the DeliveryStrategy used to hand messages to subscribers. Only the last
+// strategy applied is used.
+func WithDelivery(strategy DeliveryStrategy) TopicOption {
+	return func(opts *TopicOptions) {
+		opts.delivery = strategy
+	}
+}
+
+// WithQueueSize limits how many published messages can wait to be delivered. What happens when the
+// queue is full depends on the OverflowPolicy (see WithOverflowPolicy). Zero or less means there is
+// no limit (default). This only applies to topics that queue messages, like the AsyncTopic, and it
+// must be set when the topic is created.
+func WithQueueSize(n int) TopicOption {
+	return func(opts *TopicOptions) {
+		opts.queueSize = max(n, 0)
+	}
+}
+
+// WithOverflowPolicy sets what happens when publishing to a topic whose queue is full. It must be
+// set when the topic is created.
+func WithOverflowPolicy(policy OverflowPolicy) TopicOption {
+	return func(opts *TopicOptions) {
+		opts.overflow = policy
+	}
+}
+
+// WithReplayLatest makes the topic retain the last delivered message and deliver it to each new
+// subscriber as soon as it is registered, before any message published afterwards. There is no gap
+// nor duplicate between the replayed message and the following ones. This is useful for topics
+// carrying state or configuration where late subscribers need the current value right away. Handlers
+// registered with SubscribeAs only get the message if it is of their type. It must be set when the
+// topic is created.
+func WithReplayLatest() TopicOption {
+	return func(opts *TopicOptions) {
+		opts.replayLatest = true
+	}
+}
+
+// WithVisibilityTimeout sets how long an AckSubscriber has to acknowledge or reject a message before
+// it is delivered again.
+func WithVisibilityTimeout(d time.Duration) TopicOption {
+	return func(opts *TopicOptions) {
+		opts.visibilityTimeout = d
+	}
+}
@@ -39,6 +39,22 @@ func TestTriggerSubscribe(t *testing.T) {
 	}
 }
 
+func TestTriggerPanic(t *testing.T) {
+	to := TopicOptions{}
+
+	var calls int
+	to.Apply(
+		WithOnPanic(func(recovered, msg any) { calls++ }),
+		WithOnPanic(func(recovered, msg any) { calls++ }),
+		WithOnPanic(func(recovered, msg any) { calls++ }))
+
+	to.TriggerPanic("boom", 1)
+
+	if calls != 3 {
+		t.Fatalf("wants 3 calls but got %d", calls)
+	}
+}
+
 func TestWithOnClose(t *testing.T) {
 	type closable interface {
 		Close()
@@ -125,3 +141,30 @@ func TestWithOnSubscribe(t *testing.T) {
 		})
 	}
 }
+
+func TestWithDelivery(t *testing.T) {
+	to := TopicOptions{}
+
+	assert.Equal(t, SequentialDelivery(), to.Delivery(), "expected sequential delivery by default")
+
+	parallel := ParallelDelivery(4)
+	to.Apply(WithDelivery(parallel))
+
+	assert.Equal(t, parallel, to.Delivery())
+}
+
+func TestWithQueueSize(t *testing.T) {
+	to := TopicOptions{}
+
+	assert.Equal(t, 0, to.QueueSize(), "expected unbounded queue by default")
+	assert.Equal(t, OverflowBlock, to.Overflow(), "expected blocking by default")
+
+	to.Apply(WithQueueSize(10), WithOverflowPolicy(OverflowDropOldest))
+
+	assert.Equal(t, 10, to.QueueSize())
+	assert.Equal(t, OverflowDropOldest, to.Overflow())
+
+	to.Apply(WithQueueSize(-1))
+
+	assert.Equal(t, 0, to.QueueSize(), "expected negative sizes to mean unbounded")
+}
@@ -0,0 +1,163 @@
+package gubgub
+
+import (
+	"context"
+	"sync"
+)
+
+// queue is a multi-producer single-consumer FIFO queue. Producers never spawn go routines and, once
+// the underlying ring buffer has grown to fit the usual backlog, pushing doesn't allocate.
+// The consumer waits on the Wake channel and then pops until the queue is empty.
+type queue[T any] struct {
+	mu     sync.Mutex
+	items  ring[T]
+	size   int            // maximum number of items, zero means unbounded
+	policy OverflowPolicy // what to do when pushing to a full queue
+	closed bool
+
+	space chan struct{} // closed when an item leaves the queue, if anyone is waiting for room
+	wake  chan struct{} // signals the consumer that there are items, closed with the queue
+}
+
+func newQueue[T any](size int, policy OverflowPolicy) *queue[T] {
+	return &queue[T]{
+		size:   size,
+		policy: policy,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Push adds v to the end of the queue following the overflow policy if the queue is full. Returns
+// ErrTopicClosed if the queue is closed.
+func (q *queue[T]) Push(ctx context.Context, v T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			return ErrTopicClosed
+		}
+
+		if q.size == 0 || q.items.Len() < q.size {
+			break
+		}
+
+		switch q.policy {
+		case OverflowDropNewest:
+			return nil
+
+		case OverflowDropOldest:
+			q.items.Pop()
+
+		case OverflowReturnError:
+			return ErrTopicFull
+
+		default:
+			if err := q.waitForSpace(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	q.items.Push(v)
+	q.signal()
+
+	return nil
+}
+
+// waitForSpace releases the lock until an item leaves the queue, the queue is closed or ctx is done.
+// The lock must be held.
+func (q *queue[T]) waitForSpace(ctx context.Context) error {
+	if q.space == nil {
+		q.space = make(chan struct{})
+	}
+	space := q.space
+
+	q.mu.Unlock()
+	defer q.mu.Lock()
+
+	select {
+	case <-space:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pop removes and returns the oldest item in the queue. Returns false if the queue is empty.
+func (q *queue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	v, ok := q.items.Pop()
+
+	if ok && q.space != nil {
+		close(q.space)
+		q.space = nil
+	}
+
+	return v, ok
+}
+
+// Drain removes and returns every item in the queue.
+func (q *queue[T]) Drain() []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make([]T, 0, q.items.Len())
+	for v, ok := q.items.Pop(); ok; v, ok = q.items.Pop() {
+		items = append(items, v)
+	}
+
+	if q.space != nil {
+		close(q.space)
+		q.space = nil
+	}
+
+	return items
+}
+
+// Signal wakes up the consumer even if there are no items. This can be used to have the consumer
+// do some other work. Signaling a closed queue does nothing.
+func (q *queue[T]) Signal() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.signal()
+	}
+}
+
+// signal wakes up the consumer. The consumer is expected to pop until the queue is empty once woken
+// up so a pending signal is enough. The lock must be held.
+func (q *queue[T]) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wake returns a channel that receives a value whenever the consumer should pop items. The channel
+// is closed once the queue is closed and at that point it might still have items left.
+func (q *queue[T]) Wake() <-chan struct{} {
+	return q.wake
+}
+
+// Close prevents further pushes. Producers waiting for room are released with ErrTopicClosed. This
+// is idempotent.
+func (q *queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.closed = true
+	close(q.wake)
+
+	if q.space != nil {
+		close(q.space)
+		q.space = nil
+	}
+}
@@ -0,0 +1,86 @@
+package gubgub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_FIFO(t *testing.T) {
+	q := newQueue[int](0, OverflowBlock)
+
+	for i := range 100 {
+		require.NoError(t, q.Push(context.Background(), i))
+	}
+
+	select {
+	case <-q.Wake():
+	default:
+		t.Fatalf("expected consumer to be woken up")
+	}
+
+	for i := range 100 {
+		v, ok := q.Pop()
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+
+	_, ok := q.Pop()
+	assert.False(t, ok, "expected empty queue")
+}
+
+func TestQueue_Close(t *testing.T) {
+	q := newQueue[int](1, OverflowBlock)
+
+	require.NoError(t, q.Push(context.Background(), 1))
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- q.Push(context.Background(), 2)
+	}()
+
+	q.Close()
+	q.Close() // closing is idempotent
+
+	select {
+	case err := <-blocked:
+		assert.ErrorIs(t, err, ErrTopicClosed, "expected blocked producer to be released")
+	case <-testTimer(t, time.Second).C:
+		t.Fatalf("expected blocked producer to be released by now")
+	}
+
+	assert.ErrorIs(t, q.Push(context.Background(), 3), ErrTopicClosed)
+
+	v, ok := q.Pop()
+	require.True(t, ok, "expected items pushed before closing to remain in the queue")
+	assert.Equal(t, 1, v)
+
+	q.Signal() // signaling a closed queue must not panic
+
+	<-q.Wake()
+	_, more := <-q.Wake()
+	assert.False(t, more, "expected wake channel to be closed")
+}
+
+func TestQueue_PushDoesNotAllocate(t *testing.T) {
+	q := newQueue[int](0, OverflowBlock)
+	ctx := context.Background()
+
+	// Let the ring buffer grow to fit the backlog first.
+	for i := range 64 {
+		require.NoError(t, q.Push(ctx, i))
+	}
+	for range 64 {
+		q.Pop()
+	}
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		_ = q.Push(ctx, 1)
+		q.Pop()
+	})
+
+	assert.Zero(t, allocs)
+}
@@ -0,0 +1,22 @@
+package gubgub
+
+// latest retains the last message delivered by a topic so that it can be replayed to new
+// subscribers. The zero value holds no message.
+type latest[T any] struct {
+	msg T
+	ok  bool
+}
+
+func (l *latest[T]) set(msg T) {
+	l.msg, l.ok = msg, true
+}
+
+// replay delivers the retained message, if any, to s. Returns false if s must not be added to the
+// topic because it unsubscribed.
+func (l *latest[T]) replay(s *subscription[T]) bool {
+	if !l.ok {
+		return true
+	}
+
+	return s.safeDeliver(l.msg)
+}
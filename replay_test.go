@@ -0,0 +1,174 @@
+package gubgub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReplayLatest(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			topic := tc.newTopic(WithReplayLatest())
+
+			first := make(chan int, 10)
+			require.NoError(t, topic.Subscribe(Forever(func(i int) { first <- i })))
+
+			require.NoError(t, topic.Publish(1))
+			require.NoError(t, topic.Publish(2))
+
+			// Wait for the messages to be delivered so that 2 is the latest one.
+			assert.Equal(t, 1, <-first)
+			assert.Equal(t, 2, <-first)
+
+			second := make(chan int, 10)
+			require.NoError(t, topic.Subscribe(Forever(func(i int) { second <- i })))
+
+			require.NoError(t, topic.Publish(3))
+			topic.Close()
+
+			assert.Equal(t, 3, <-first)
+
+			var received []int
+			for i := range second {
+				received = append(received, i)
+				if len(received) == 2 {
+					break
+				}
+			}
+
+			assert.Equal(t, []int{2, 3}, received, "expected the latest message replayed before new ones")
+		})
+	}
+}
+
+func TestWithReplayLatest_Nothing(t *testing.T) {
+	topic := NewSyncTopic[int](WithReplayLatest())
+	defer topic.Close()
+
+	var received []int
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		received = append(received, i)
+	})))
+
+	assert.Empty(t, received, "expected no replay before anything was published")
+
+	require.NoError(t, topic.Publish(1))
+	assert.Equal(t, []int{1}, received)
+}
+
+func TestWithReplayLatest_Disabled(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	require.NoError(t, topic.Publish(1))
+
+	var received []int
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		received = append(received, i)
+	})))
+
+	assert.Empty(t, received, "expected no replay without the option")
+}
+
+func TestWithReplayLatest_UnsubscribeOnReplay(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			topic := tc.newTopic(WithReplayLatest())
+			defer topic.Close()
+
+			delivered := make(chan struct{})
+			require.NoError(t, topic.Subscribe(func(int) bool {
+				close(delivered)
+				return false
+			}))
+			require.NoError(t, topic.Publish(1))
+			<-delivered
+
+			var mu sync.Mutex
+			var received []int
+			sub, err := topic.SubscribeWithHandle(func(i int) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, i)
+				return false
+			})
+			require.NoError(t, err)
+
+			select {
+			case <-sub.Done():
+			case <-time.After(time.Second):
+				t.Fatalf("expected the subscriber to be removed after the replay")
+			}
+
+			require.NoError(t, topic.Publish(2))
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []int{1}, received)
+		})
+	}
+}
+
+func TestWithReplayLatest_SubscribeAs(t *testing.T) {
+	for _, tc := range topicCases[domainEvent]() {
+		t.Run(tc.name, func(t *testing.T) {
+			topic := tc.newTopic(WithReplayLatest())
+
+			delivered := make(chan domainEvent, 10)
+			require.NoError(t, topic.Subscribe(Forever(func(e domainEvent) { delivered <- e })))
+
+			require.NoError(t, topic.Publish(accountOpened{Account: "a"}))
+			require.NoError(t, topic.Publish(accountClosed{Account: "a"}))
+
+			// Wait for the messages to be delivered so that the closed account is the latest one.
+			<-delivered
+			<-delivered
+
+			opened := make(chan domainEvent, 10)
+			_, err := SubscribeAs(topic, func(e accountOpened) bool {
+				opened <- e
+				return true
+			})
+			require.NoError(t, err)
+
+			closed := make(chan domainEvent, 10)
+			_, err = SubscribeAs(topic, func(e accountClosed) bool {
+				closed <- e
+				return true
+			})
+			require.NoError(t, err)
+
+			final := make(chan domainEvent, 10)
+			_, err = SubscribeAs(topic, func(e interface{ Final() bool }) bool {
+				final <- e.(domainEvent)
+				return true
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, topic.Publish(accountOpened{Account: "b"}))
+			require.NoError(t, topic.Publish(accountClosed{Account: "b"}))
+			topic.Close()
+
+			assert.Equal(t, []domainEvent{accountOpened{Account: "b"}}, drain(opened),
+				"expected no replay to handlers of another type")
+			assert.Equal(t, []domainEvent{accountClosed{Account: "a"}, accountClosed{Account: "b"}}, drain(closed),
+				"expected the latest message replayed before new ones")
+			assert.Equal(t, []domainEvent{accountClosed{Account: "a"}, accountClosed{Account: "b"}}, drain(final),
+				"expected the latest message replayed to handlers of an interface it implements")
+		})
+	}
+}
+
+// drain returns the messages buffered in ch.
+func drain[T any](ch chan T) []T {
+	var msgs []T
+	for len(ch) > 0 {
+		msgs = append(msgs, <-ch)
+	}
+
+	return msgs
+}
@@ -0,0 +1,167 @@
+package gubgub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Requester publishes requests to a topic and waits for the correlated responses on another topic.
+// Requests and responses are correlated by an ID that both must carry.
+type Requester[Req, Resp any] struct {
+	requests   Publishable[Req]
+	requestID  func(Req) string
+	responseID func(Resp) string
+	sub        Subscription // the subscription to the responses topic
+
+	mu      sync.Mutex
+	pending map[string]chan Resp // waiting requests by ID
+	closed  chan struct{}        // closed once the requester is closed
+}
+
+// NewRequester creates a Requester that publishes requests to the requests topic and subscribes to the
+// responses topic. The requestID and responseID funcs extract the ID used to match a response to its
+// request. Responses nobody is waiting for are ignored.
+func NewRequester[Req, Resp any](
+	requests Publishable[Req],
+	responses HandleSubscribable[Resp],
+	requestID func(Req) string,
+	responseID func(Resp) string,
+) (*Requester[Req, Resp], error) {
+	r := &Requester[Req, Resp]{
+		requests:   requests,
+		requestID:  requestID,
+		responseID: responseID,
+		pending:    make(map[string]chan Resp),
+		closed:     make(chan struct{}),
+	}
+
+	sub, err := responses.SubscribeWithHandle(r.receive)
+	if err != nil {
+		return nil, fmt.Errorf("new requester: %w", err)
+	}
+	r.sub = sub
+
+	return r, nil
+}
+
+// Request publishes req and waits for its response. It gives up and returns ctx.Err() once ctx is
+// done. Only one request with a given ID can wait for a response at a time. If the requests topic
+// implements ContextPublishable then ctx is used for publishing too.
+func (r *Requester[Req, Resp]) Request(ctx context.Context, req Req) (Resp, error) {
+	var zero Resp
+
+	id := r.requestID(req)
+
+	response, err := r.wait(id)
+	if err != nil {
+		return zero, err
+	}
+	defer r.forget(id)
+
+	if p, ok := r.requests.(ContextPublishable[Req]); ok {
+		err = p.PublishContext(ctx, req)
+	} else {
+		err = r.requests.Publish(req)
+	}
+
+	if err != nil {
+		return zero, fmt.Errorf("requester request: %w", err)
+	}
+
+	select {
+	case resp := <-response:
+		return resp, nil
+	case <-ctx.Done():
+		return zero, fmt.Errorf("requester request: %w", ctx.Err())
+	case <-r.closed:
+		return zero, fmt.Errorf("requester request: %w", ErrRequesterClosed)
+	}
+}
+
+// Close stops listening for responses by unsubscribing from the responses topic. Requests waiting
+// for a response fail with ErrRequesterClosed. This is idempotent and thread safe.
+func (r *Requester[Req, Resp]) Close() {
+	r.mu.Lock()
+
+	select {
+	case <-r.closed:
+	default:
+		close(r.closed)
+	}
+
+	r.mu.Unlock()
+
+	r.sub.Unsubscribe()
+}
+
+// wait registers a request waiting for a response with the given ID.
+func (r *Requester[Req, Resp]) wait(id string) (chan Resp, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return nil, fmt.Errorf("requester request: %w", ErrRequesterClosed)
+	default:
+	}
+
+	if _, ok := r.pending[id]; ok {
+		return nil, fmt.Errorf("requester request: request %q is already waiting for a response", id)
+	}
+
+	response := make(chan Resp, 1)
+	r.pending[id] = response
+
+	return response, nil
+}
+
+// forget stops waiting for a response with the given ID.
+func (r *Requester[Req, Resp]) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pending, id)
+}
+
+// receive hands resp to the request waiting for it, if any. Returns false once the requester is
+// closed in case a response is delivered before Close unsubscribes.
+func (r *Requester[Req, Resp]) receive(resp Resp) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return false
+	default:
+	}
+
+	id := r.responseID(resp)
+
+	if response, ok := r.pending[id]; ok {
+		delete(r.pending, id)
+		response <- resp // never blocks since only one response is ever sent
+	}
+
+	return true
+}
+
+// Responder returns a subscriber for the serving side of a Requester. It calls handler for each
+// request and publishes the response to the responses topic. The handler is responsible for copying
+// the request ID into the response. Errors from the handler or from publishing the response are
+// returned so that they are reported like any other ErrorSubscriber failure, in which case no
+// response is published and the request eventually times out.
+func Responder[Req, Resp any](responses Publishable[Resp], handler func(Req) (Resp, error)) ErrorSubscriber[Req] {
+	return func(req Req) error {
+		resp, err := handler(req)
+		if err != nil {
+			return err
+		}
+
+		if err := responses.Publish(resp); err != nil {
+			return fmt.Errorf("responder publish: %w", err)
+		}
+
+		return nil
+	}
+}
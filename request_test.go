@@ -0,0 +1,167 @@
+package gubgub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRequest struct {
+	ID   string
+	Text string
+}
+
+type testResponse struct {
+	ID   string
+	Text string
+}
+
+func TestRequester(t *testing.T) {
+	testCases := []struct {
+		name     string
+		newTopic func() (Topic[testRequest], Topic[testResponse])
+	}{
+		{
+			name: "sync topics",
+			newTopic: func() (Topic[testRequest], Topic[testResponse]) {
+				return NewSyncTopic[testRequest](), NewSyncTopic[testResponse]()
+			},
+		},
+		{
+			name: "async topics",
+			newTopic: func() (Topic[testRequest], Topic[testResponse]) {
+				return NewAsyncTopic[testRequest](), NewAsyncTopic[testResponse]()
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests, responses := tc.newTopic()
+			defer requests.Close()
+			defer responses.Close()
+
+			_, err := requests.SubscribeErr(Responder(responses, func(req testRequest) (testResponse, error) {
+				return testResponse{ID: req.ID, Text: strings.ToUpper(req.Text)}, nil
+			}))
+			require.NoError(t, err)
+
+			r := newTestRequester(t, requests, responses)
+			defer r.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			for _, id := range []string{"1", "2", "3"} {
+				resp, err := r.Request(ctx, testRequest{ID: id, Text: "hello " + id})
+				require.NoError(t, err)
+				assert.Equal(t, testResponse{ID: id, Text: "HELLO " + id}, resp)
+			}
+		})
+	}
+}
+
+func TestRequester_Timeout(t *testing.T) {
+	requests, responses := NewSyncTopic[testRequest](), NewSyncTopic[testResponse]()
+	defer requests.Close()
+	defer responses.Close()
+
+	errFailed := errors.New("failed")
+
+	var failures int
+	requests.SetOptions(WithOnError(func(msg any, err error, sub Subscription) {
+		assert.ErrorIs(t, err, errFailed)
+		failures++
+	}))
+
+	_, err := requests.SubscribeErr(Responder(responses, func(req testRequest) (testResponse, error) {
+		return testResponse{}, errFailed
+	}))
+	require.NoError(t, err)
+
+	r := newTestRequester(t, requests, responses)
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = r.Request(ctx, testRequest{ID: "1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, failures)
+
+	assert.Empty(t, r.pending, "expected abandoned requests to be forgotten")
+}
+
+func TestRequester_Close(t *testing.T) {
+	requests, responses := NewSyncTopic[testRequest](), NewSyncTopic[testResponse]()
+	defer requests.Close()
+	defer responses.Close()
+
+	r := newTestRequester(t, requests, responses)
+
+	result := make(chan error)
+	go func() {
+		_, err := r.Request(context.Background(), testRequest{ID: "1"}) // nobody responds
+		result <- err
+	}()
+
+	// Wait for the request to be pending.
+	for {
+		r.mu.Lock()
+		n := len(r.pending)
+		r.mu.Unlock()
+
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	r.Close()
+	assertClosed(t, r.sub.Done())
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, ErrRequesterClosed)
+	case <-timeout.C:
+		t.Fatalf("expected pending request to fail by now")
+	}
+
+	_, err := r.Request(context.Background(), testRequest{ID: "2"})
+	assert.ErrorIs(t, err, ErrRequesterClosed)
+}
+
+func TestRequester_DuplicateID(t *testing.T) {
+	requests, responses := NewSyncTopic[testRequest](), NewSyncTopic[testResponse]()
+	defer requests.Close()
+	defer responses.Close()
+
+	r := newTestRequester(t, requests, responses)
+	defer r.Close()
+
+	_, err := r.wait("1")
+	require.NoError(t, err)
+
+	_, err = r.Request(context.Background(), testRequest{ID: "1"})
+	assert.Error(t, err)
+}
+
+func newTestRequester(t testing.TB, requests Publishable[testRequest], responses HandleSubscribable[testResponse]) *Requester[testRequest, testResponse] {
+	t.Helper()
+
+	r, err := NewRequester(
+		requests,
+		responses,
+		func(req testRequest) string { return req.ID },
+		func(resp testResponse) string { return resp.ID },
+	)
+	require.NoError(t, err)
+
+	return r
+}
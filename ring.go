@@ -0,0 +1,51 @@
+package gubgub
+
+// ring is a FIFO queue backed by a circular buffer which grows as needed. Unlike re-slicing a
+// regular slice, popping items allows their space to be reused. The zero value is an empty ring
+// ready to use.
+type ring[T any] struct {
+	items []T
+	head  int // position of the oldest item
+	size  int // number of items
+}
+
+func (r *ring[T]) Len() int {
+	return r.size
+}
+
+// Push adds v to the end of the queue.
+func (r *ring[T]) Push(v T) {
+	if r.size == len(r.items) {
+		r.grow()
+	}
+
+	r.items[(r.head+r.size)%len(r.items)] = v
+	r.size++
+}
+
+// Pop removes and returns the oldest item of the queue. Returns false if the queue is empty.
+func (r *ring[T]) Pop() (T, bool) {
+	var zero T
+
+	if r.size == 0 {
+		return zero, false
+	}
+
+	v := r.items[r.head]
+	r.items[r.head] = zero // allow the item to be garbage collected
+
+	r.head = (r.head + 1) % len(r.items)
+	r.size--
+
+	return v, true
+}
+
+func (r *ring[T]) grow() {
+	items := make([]T, max(2*len(r.items), 8))
+
+	n := copy(items, r.items[r.head:])
+	copy(items[n:], r.items[:r.head])
+
+	r.items = items
+	r.head = 0
+}
@@ -0,0 +1,38 @@
+package gubgub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	var r ring[int]
+
+	_, ok := r.Pop()
+	assert.False(t, ok, "expected empty ring")
+
+	next := 0
+	for i := range 100 {
+		r.Push(i)
+
+		if i%3 == 0 { // pop every now and then so that items wrap around the buffer
+			v, ok := r.Pop()
+			require.True(t, ok)
+			require.Equal(t, next, v)
+			next++
+		}
+	}
+
+	assert.Equal(t, 100-next, r.Len())
+
+	for r.Len() > 0 {
+		v, ok := r.Pop()
+		require.True(t, ok)
+		require.Equal(t, next, v)
+		next++
+	}
+
+	assert.Equal(t, 100, next)
+}
@@ -0,0 +1,221 @@
+package gubgub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Subscription is a handle to a subscriber registered in a topic.
+type Subscription interface {
+	// Unsubscribe removes the subscriber from the topic. The subscriber will not be called again
+	// once this returns, except maybe for a message whose delivery was already in progress. This
+	// is idempotent, thread safe and it is safe to call from within the subscriber itself.
+	Unsubscribe()
+
+	// Done returns a channel that is closed once the subscriber is removed from the topic. This
+	// happens when the subscriber returns false, Unsubscribe is called or the topic is closed.
+	Done() <-chan struct{}
+}
+
+// subscription holds a Subscriber registered in a topic. Each subscription knows its position in
+// the subscribers slice of the topic so that it can be removed in constant time.
+type subscription[T any] struct {
+	// fn and unsubscribed are read for every message so they are kept next to each other, at the
+	// start of the struct, where they always share the same cache line.
+	fn           Subscriber[T]
+	unsubscribed atomic.Bool
+
+	// options of the topic this subscription belongs to.
+	options *TopicOptions
+
+	// index is the position of this subscription in the subscribers slice of the topic or -1 if it
+	// is not part of that slice. Only the topic may read or write this value.
+	index int
+
+	done chan struct{}
+
+	// unsubscribe asks the topic to remove this subscription.
+	unsubscribe func(*subscription[T])
+
+	// stopContext stops watching the context this subscription is bound to, if any.
+	stopContext func() bool
+
+	// closer is closed once the subscription is removed, if the subscriber has a lifecycle.
+	closer Closer
+}
+
+func newSubscription[T any](fn Subscriber[T], options *TopicOptions, unsubscribe func(*subscription[T])) *subscription[T] {
+	return &subscription[T]{
+		fn:          fn,
+		options:     options,
+		index:       -1,
+		done:        make(chan struct{}),
+		unsubscribe: unsubscribe,
+	}
+}
+
+func newErrSubscription[T any](fn ErrorSubscriber[T], options *TopicOptions, unsubscribe func(*subscription[T])) *subscription[T] {
+	s := newSubscription(nil, options, unsubscribe)
+	s.fn = func(msg T) bool {
+		return s.deliverErr(fn, msg)
+	}
+
+	return s
+}
+
+func newClosableSubscription[T any](sub ClosableSubscriber[T], options *TopicOptions, unsubscribe func(*subscription[T])) *subscription[T] {
+	s := newSubscription(sub.Receive, options, unsubscribe)
+	s.closer = sub
+
+	return s
+}
+
+// bind unsubscribes s once ctx is done. This must be called before the subscription is handed to the
+// topic.
+func (s *subscription[T]) bind(ctx context.Context) {
+	s.stopContext = context.AfterFunc(ctx, s.Unsubscribe)
+}
+
+func (s *subscription[T]) Unsubscribe() {
+	if s.unsubscribed.Swap(true) {
+		return
+	}
+
+	s.unsubscribe(s)
+}
+
+func (s *subscription[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// deliver calls the subscriber unless it has been unsubscribed and returns false if the
+// subscription should be removed. A panic in the subscriber is not recovered here: delivery loops
+// recover it once for all subscribers and hand it to recovered, see sequentialDelivery.
+func (s *subscription[T]) deliver(msg T) bool {
+	return !s.unsubscribed.Load() && s.fn(msg)
+}
+
+// safeDeliver is like deliver but also recovers a panic in the subscriber. Use it to deliver to a
+// single subscriber outside of a delivery loop.
+func (s *subscription[T]) safeDeliver(msg T) (keep bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			keep = s.recovered(r, msg)
+		}
+	}()
+
+	return s.deliver(msg)
+}
+
+// recovered handles a panic in the subscriber according to the topic options and returns false if
+// the subscription should be removed.
+func (s *subscription[T]) recovered(r any, msg T) bool {
+	s.options.TriggerPanic(r, msg)
+	s.options.TriggerDeadLetter(msg, nil, r)
+
+	return s.options.PanicPolicy() == PanicKeep
+}
+
+// deliverErr calls the error returning subscriber and reports failures to the topic options.
+func (s *subscription[T]) deliverErr(fn ErrorSubscriber[T], msg T) bool {
+	err := fn(msg)
+	if err == nil {
+		return true
+	}
+
+	if err != ErrUnsubscribe {
+		s.options.TriggerError(msg, err, s)
+		s.options.TriggerDeadLetter(msg, err, nil)
+	}
+
+	return !errors.Is(err, ErrUnsubscribe)
+}
+
+// release marks the subscription as no longer part of the topic. A closable subscriber is closed in
+// the background since this usually happens while delivering messages.
+func (s *subscription[T]) release() {
+	s.detach()
+
+	if s.closer != nil {
+		go s.closer.Close()
+	}
+}
+
+// detach marks the subscription as no longer part of the topic without closing the subscriber.
+func (s *subscription[T]) detach() {
+	s.index = -1
+	close(s.done)
+
+	if s.stopContext != nil {
+		s.stopContext()
+	}
+}
+
+// addSubscription appends s to the subscribers unless it was already unsubscribed.
+func addSubscription[T any](subscribers []*subscription[T], s *subscription[T]) []*subscription[T] {
+	if s.unsubscribed.Load() {
+		s.release()
+		return subscribers
+	}
+
+	s.index = len(subscribers)
+
+	return append(subscribers, s)
+}
+
+// removeSubscription removes s from the subscribers in constant time by replacing it with the last
+// subscriber. Removing a subscription that is not part of the subscribers does nothing.
+func removeSubscription[T any](subscribers []*subscription[T], s *subscription[T]) []*subscription[T] {
+	if s.index < 0 {
+		return subscribers
+	}
+
+	last := len(subscribers) - 1
+
+	subscribers[s.index] = subscribers[last]
+	subscribers[s.index].index = s.index
+	subscribers[last] = nil
+
+	s.release()
+
+	return subscribers[:last]
+}
+
+// releaseSubscriptions removes all subscribers. This is meant to be used once the topic is closed.
+// Unlike release, closable subscribers are closed before this returns so that closing the topic
+// waits for them.
+func releaseSubscriptions[T any](subscribers []*subscription[T]) []*subscription[T] {
+	for _, s := range subscribers {
+		s.detach()
+	}
+
+	for i, s := range subscribers {
+		if s.closer != nil {
+			s.closer.Close()
+		}
+
+		subscribers[i] = nil
+	}
+
+	return subscribers[:0]
+}
+
+// withLock runs fn while holding mu. Subscribers are called while such a lock is held so, if mu is
+// not immediately available, fn runs in a new go routine once mu is free instead of waiting for it.
+// This allows subscribers to unsubscribe without deadlocking while making sure a subscription is
+// never released while a message is being delivered to it.
+func withLock(mu *sync.Mutex, fn func()) {
+	if mu.TryLock() {
+		defer mu.Unlock()
+		fn()
+		return
+	}
+
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}()
+}
@@ -0,0 +1,452 @@
+package gubgub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveSubscription(t *testing.T) {
+	subscribers := subscriptionsOf(NoOp[int](), NoOp[int](), NoOp[int](), NoOp[int]())
+	second, last := subscribers[1], subscribers[3]
+
+	subscribers = removeSubscription(subscribers, second)
+
+	require.Len(t, subscribers, 3)
+	assert.NotContains(t, subscribers, second)
+	assert.Equal(t, -1, second.index)
+	assertClosed(t, second.Done())
+
+	for i, s := range subscribers {
+		assert.Equalf(t, i, s.index, "expected subscription at position %d to know its position", i)
+	}
+
+	subscribers = removeSubscription(subscribers, last)
+	subscribers = removeSubscription(subscribers, last) // removing twice does nothing
+
+	require.Len(t, subscribers, 2)
+	assert.NotContains(t, subscribers, last)
+}
+
+func TestAddSubscription_Unsubscribed(t *testing.T) {
+	s := newTestSubscription(NoOp[int]())
+	s.Unsubscribe()
+
+	subscribers := addSubscription(nil, s)
+
+	assert.Empty(t, subscribers)
+	assertClosed(t, s.Done())
+}
+
+func TestSubscription_Unsubscribe(t *testing.T) {
+	testCases := []struct {
+		name  string
+		topic Topic[int]
+	}{
+		{
+			name:  "sync topic",
+			topic: NewSyncTopic[int](),
+		},
+		{
+			name:  "async topic",
+			topic: NewAsyncTopic[int](),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(tc.topic.Close)
+
+			feedback := make(chan int, 1)
+
+			sub, err := tc.topic.SubscribeWithHandle(Forever(func(i int) {
+				feedback <- i
+			}))
+			require.NoError(t, err)
+
+			sub.Unsubscribe()
+			sub.Unsubscribe() // unsubscribing is idempotent
+
+			select {
+			case <-sub.Done():
+			case <-testTimer(t, time.Second).C:
+				t.Fatalf("expected subscription to be done by now")
+			}
+
+			require.NoError(t, tc.topic.Publish(1))
+			tc.topic.Close()
+
+			assert.Empty(t, feedback, "expected no messages after unsubscribing")
+		})
+	}
+}
+
+func TestSubscription_Done(t *testing.T) {
+	removals := []struct {
+		name   string
+		remove func(Topic[int])
+	}{
+		{
+			name:   "subscriber returns false",
+			remove: func(topic Topic[int]) { _ = topic.Publish(1) },
+		},
+		{
+			name:   "closed",
+			remove: func(topic Topic[int]) { topic.Close() },
+		},
+	}
+	for _, tc := range topicCases[int]() {
+		for _, rc := range removals {
+			t.Run(tc.name+" "+rc.name, func(t *testing.T) {
+				onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+				topic := tc.newTopic(onSubscribe)
+				t.Cleanup(topic.Close)
+
+				sub, err := topic.SubscribeWithHandle(func(int) bool { return false })
+				require.NoError(t, err)
+
+				<-subscriberReady
+
+				rc.remove(topic)
+
+				select {
+				case <-sub.Done():
+				case <-testTimer(t, time.Second).C:
+					t.Fatalf("expected subscription to be done by now")
+				}
+			})
+		}
+	}
+}
+
+func TestSubscribeContext(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			onSubscribe, subscriberReady := withNotifyOnNthSubscriber(t, 1)
+			topic := tc.newTopic(onSubscribe)
+			t.Cleanup(topic.Close)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			feedback := make(chan int, 2)
+
+			err := topic.SubscribeContext(ctx, Forever(func(i int) {
+				feedback <- i
+			}))
+			require.NoError(t, err)
+
+			<-subscriberReady
+
+			require.NoError(t, topic.Publish(1))
+
+			select {
+			case i := <-feedback:
+				assert.Equal(t, 1, i)
+			case <-testTimer(t, time.Second).C:
+				t.Fatalf("expected feedback by now")
+			}
+
+			cancel()
+
+			// The subscription is removed asynchronously so keep publishing until a message is
+			// no longer delivered.
+			timeout := testTimer(t, time.Second)
+			for {
+				require.NoError(t, topic.Publish(2))
+
+				select {
+				case <-feedback:
+				case <-timeout.C:
+					t.Fatalf("expected subscriber to be removed by now")
+				case <-time.After(10 * time.Millisecond):
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestSyncTopic_UnsubscribeFromSubscriber(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	t.Cleanup(topic.Close)
+
+	var (
+		sub   Subscription
+		calls int
+	)
+
+	sub, err := topic.SubscribeWithHandle(Forever(func(int) {
+		calls++
+		sub.Unsubscribe() // must not deadlock while Publish holds the lock
+	}))
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+	require.NoError(t, topic.Publish(2))
+
+	<-sub.Done()
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestSubscriberPanic(t *testing.T) {
+	policies := []struct {
+		name     string
+		policy   PanicPolicy
+		expCalls int
+	}{
+		{
+			name:     "drops subscriber",
+			policy:   PanicDrop,
+			expCalls: 1,
+		},
+		{
+			name:     "keeps subscriber",
+			policy:   PanicKeep,
+			expCalls: 3,
+		},
+	}
+	for _, tc := range topicCases[int]() {
+		for _, pc := range policies {
+			t.Run(tc.name+" "+pc.name, func(t *testing.T) {
+				var (
+					recovered []any
+					panicked  []any
+				)
+
+				topic := tc.newTopic(
+					WithPanicPolicy(pc.policy),
+					WithOnPanic(func(r, msg any) {
+						recovered = append(recovered, r)
+						panicked = append(panicked, msg)
+					}),
+				)
+
+				var calls, healthy int
+
+				require.NoError(t, topic.Subscribe(Forever(func(i int) {
+					calls++
+					panic("boom")
+				})))
+
+				require.NoError(t, topic.Subscribe(Forever(func(i int) {
+					healthy++
+				})))
+
+				for i := range 3 {
+					require.NoError(t, topic.Publish(i))
+				}
+
+				topic.Close()
+
+				assert.Equal(t, pc.expCalls, calls)
+				assert.Equal(t, 3, healthy, "expected other subscribers not to be affected")
+				assert.Len(t, recovered, pc.expCalls)
+				assert.Equal(t, "boom", recovered[0])
+				assert.Equal(t, 0, panicked[0])
+			})
+		}
+	}
+}
+
+func TestSubscribeErr(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			errFailed := errors.New("failed")
+
+			type failure struct {
+				msg any
+				err error
+				sub Subscription
+			}
+			var failures []failure
+
+			topic := tc.newTopic(WithOnError(func(msg any, err error, sub Subscription) {
+				failures = append(failures, failure{msg: msg, err: err, sub: sub})
+			}))
+
+			var calls int
+
+			sub, err := topic.SubscribeErr(func(i int) error {
+				calls++
+
+				switch i {
+				case 1:
+					return errFailed
+				case 2:
+					return ErrUnsubscribe
+				default:
+					return nil
+				}
+			})
+			require.NoError(t, err)
+
+			for i := range 4 {
+				require.NoError(t, topic.Publish(i))
+			}
+
+			topic.Close()
+
+			assert.Equal(t, 3, calls, "expected subscriber to unsubscribe with ErrUnsubscribe")
+			require.Len(t, failures, 1, "expected only failures to be reported")
+			assert.Equal(t, 1, failures[0].msg)
+			assert.ErrorIs(t, failures[0].err, errFailed)
+			assert.Equal(t, sub, failures[0].sub)
+		})
+	}
+}
+
+func TestSubscribeErr_WrappedUnsubscribe(t *testing.T) {
+	var failures int
+
+	topic := NewSyncTopic[int](WithOnError(func(msg any, err error, sub Subscription) {
+		failures++
+	}))
+
+	sub, err := topic.SubscribeErr(func(i int) error {
+		return fmt.Errorf("giving up: %w", ErrUnsubscribe)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+
+	assertClosed(t, sub.Done())
+	assert.Equal(t, 1, failures, "expected wrapped ErrUnsubscribe to be reported")
+}
+
+func TestSubscribeClosable(t *testing.T) {
+	for _, tc := range topicCases[int]() {
+		t.Run(tc.name, func(t *testing.T) {
+			const msgCount = 10
+
+			topic := tc.newTopic()
+
+			var (
+				received  int
+				unblocked = make(chan struct{})
+			)
+
+			buffered := NewBuffered(Forever(func(int) {
+				<-unblocked // keep messages in the buffer until the topic is closing
+				received++
+			}))
+
+			sub, err := topic.SubscribeClosable(buffered)
+			require.NoError(t, err)
+
+			for i := range msgCount {
+				require.NoError(t, topic.Publish(i))
+			}
+
+			close(unblocked)
+			topic.Close()
+
+			assertClosed(t, sub.Done())
+			assert.Equal(t, msgCount, received, "expected Close to wait for the buffer to be flushed")
+			assert.False(t, buffered.Receive(msgCount), "expected closed subscriber to unsubscribe")
+		})
+	}
+}
+
+func TestSubscribeClosable_Unsubscribe(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	defer topic.Close()
+
+	closed := make(chan struct{})
+	sub, err := topic.SubscribeClosable(closableFunc[int]{
+		receive: NoOp[int](),
+		close:   func() { close(closed) },
+	})
+	require.NoError(t, err)
+
+	sub.Unsubscribe()
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case <-closed:
+	case <-timeout.C:
+		t.Fatalf("expected unsubscribed subscriber to be closed by now")
+	}
+}
+
+func TestSubscribeClosable_TopicClosed(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	topic.Close()
+
+	var closed bool
+	_, err := topic.SubscribeClosable(closableFunc[int]{
+		receive: NoOp[int](),
+		close:   func() { closed = true },
+	})
+
+	assert.ErrorIs(t, err, ErrTopicClosed)
+	assert.False(t, closed, "expected subscriber rejected by the topic not to be closed")
+}
+
+func TestSubscribeClosable_CloseDuringPublish(t *testing.T) {
+	var (
+		closedSubscriber atomic.Bool
+		onCloseOrder     = make(chan bool, 1)
+	)
+
+	topic := NewSyncTopic[int](WithOnClose(func() {
+		onCloseOrder <- closedSubscriber.Load()
+	}))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	require.NoError(t, topic.Subscribe(Once(func(int) {
+		close(started)
+		<-release // keep the publish in progress
+	})))
+
+	_, err := topic.SubscribeClosable(closableFunc[int]{
+		receive: NoOp[int](),
+		close:   func() { closedSubscriber.Store(true) },
+	})
+	require.NoError(t, err)
+
+	published := make(chan error)
+	go func() {
+		published <- topic.Publish(1)
+	}()
+
+	<-started
+
+	topic.Close() // returns right away since a message is being delivered
+	close(release)
+
+	require.NoError(t, <-published)
+	assert.True(t, <-onCloseOrder, "expected the close callback after subscribers are closed")
+
+	require.NoError(t, topic.Shutdown(context.Background()))
+	assert.True(t, closedSubscriber.Load(), "expected Shutdown to wait for closable subscribers")
+}
+
+// closableFunc is a ClosableSubscriber made of funcs.
+type closableFunc[T any] struct {
+	receive Subscriber[T]
+	close   func()
+}
+
+func (c closableFunc[T]) Receive(msg T) bool { return c.receive(msg) }
+
+func (c closableFunc[T]) Close() { c.close() }
+
+func assertClosed(t testing.TB, ch <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	default:
+		t.Errorf("expected channel to be closed")
+	}
+}
@@ -1,7 +1,9 @@
 package gubgub
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 )
@@ -11,56 +13,345 @@ import (
 type SyncTopic[T any] struct {
 	options TopicOptions
 
-	closed atomic.Bool
+	// state holds the closed flag in the lowest bit and the number of publishes in progress in the
+	// remaining bits. Keeping both in the same value allows Shutdown to know when every publish
+	// accepted before closing is done.
+	state     atomic.Int64
+	drained   chan struct{} // closed once the topic is closed and no publishes are in progress
+	drainOnce sync.Once
+
+	abandoned   chan struct{} // closed once Shutdown gave up waiting for publishes in progress
+	abandonOnce sync.Once
+
+	released chan struct{} // closed once all subscriptions are removed and the topic is closed
 
 	mu          sync.Mutex
-	subscribers []Subscriber[T]
+	subscribers []*subscription[T]
+	groups      consumerGroups[T]
+	types       typeRoutes[T]
+	latest      latest[T] // replayed to new subscribers, see WithReplayLatest
 }
 
 // NewSyncTopic creates a SyncTopic with the specified options.
 func NewSyncTopic[T any](opts ...TopicOption) *SyncTopic[T] {
-	t := &SyncTopic[T]{}
+	t := &SyncTopic[T]{
+		drained:   make(chan struct{}),
+		abandoned: make(chan struct{}),
+		released:  make(chan struct{}),
+	}
 
 	t.SetOptions(opts...)
 
 	return t
 }
 
-// Close will prevent further publishing and subscribing.
+// Close will prevent further publishing and subscribing. All subscriptions are removed and
+// closable subscribers are closed before this returns. This doesn't wait for publishes in progress,
+// use Shutdown for that. If a message is being delivered, for example when closing from within a
+// subscriber, the subscriptions are removed once that delivery is done and this returns right away.
+// Either way the WithOnClose callback is called after subscriptions are removed.
 func (t *SyncTopic[T]) Close() {
-	t.closed.Store(true)
-	t.options.TriggerClose()
+	if t.close() {
+		t.release()
+	}
+}
+
+// Shutdown prevents further publishing and subscribing and then waits for publishes in progress to
+// complete before removing all subscriptions. If ctx is done first, publishes still waiting for
+// their turn are abandoned and their messages are handed to the WithOnUndelivered callback. In that
+// case an UndeliveredError reports how many publishes didn't complete and subscriptions are removed
+// once the delivery in progress is done. An error wrapping ctx.Err() is also returned if removing
+// the subscriptions, which includes closing closable subscribers, is not done before ctx is.
+func (t *SyncTopic[T]) Shutdown(ctx context.Context) error {
+	closing := t.close()
+
+	select {
+	case <-t.drained:
+		if closing {
+			go t.release() // closing a subscriber might never return
+		}
+
+		select {
+		case <-t.released:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("sync topic shutdown: %w", ctx.Err())
+		}
+
+	case <-ctx.Done():
+	}
+
+	undelivered := int(t.state.Load() >> 1)
+
+	t.abandonOnce.Do(func() {
+		close(t.abandoned)
+	})
+
+	if closing {
+		go t.release()
+	}
+
+	if undelivered == 0 {
+		select {
+		case <-t.released:
+			return nil
+		default:
+			return fmt.Errorf("sync topic shutdown: %w", ctx.Err())
+		}
+	}
+
+	return fmt.Errorf("sync topic shutdown: %w", &UndeliveredError{Undelivered: undelivered, Err: ctx.Err()})
+}
+
+// close marks the topic as closed and reports whether this call was the one closing it.
+func (t *SyncTopic[T]) close() bool {
+	prev := t.state.Or(1)
+	if prev&1 == 1 {
+		return false
+	}
+
+	if prev == 0 { // no publishes in progress
+		t.drainOnce.Do(func() {
+			close(t.drained)
+		})
+	}
+
+	return true
+}
+
+// release removes all subscribers once the topic is closed and then calls the close callback. Like
+// withLock, this happens in a new go routine if the lock is not immediately available.
+func (t *SyncTopic[T]) release() {
+	release := func() {
+		t.subscribers = releaseSubscriptions(t.subscribers)
+		t.mu.Unlock()
+
+		t.options.TriggerClose()
+		close(t.released)
+	}
+
+	if t.mu.TryLock() {
+		release()
+		return
+	}
+
+	go func() {
+		t.mu.Lock()
+		release()
+	}()
+}
+
+func (t *SyncTopic[T]) isClosed() bool {
+	return t.state.Load()&1 == 1
+}
+
+// enter registers a publish in progress. Returns false if the topic is closed in which case the
+// publish must not proceed. Either way leave must be called afterwards.
+func (t *SyncTopic[T]) enter() bool {
+	return t.state.Add(2)&1 == 0
+}
+
+// leave unregisters a publish in progress.
+func (t *SyncTopic[T]) leave() {
+	if t.state.Add(-2) == 1 { // closed and no more publishes in progress
+		t.drainOnce.Do(func() {
+			close(t.drained)
+		})
+	}
 }
 
 // Publish broadcasts a message to all subscribers.
 func (t *SyncTopic[T]) Publish(msg T) error {
-	if t.closed.Load() {
+	return t.PublishContext(context.Background(), msg)
+}
+
+// PublishContext broadcasts a message to all subscribers. It gives up and returns ctx.Err() if
+// delivery can't start before ctx is done, for example because a slow subscriber is still handling
+// a previous message. Once delivery started it can't be interrupted: this returns only after all
+// subscribers got the message, even if ctx is done in the meantime.
+func (t *SyncTopic[T]) PublishContext(ctx context.Context, msg T) error {
+	defer t.leave()
+
+	if !t.enter() {
 		return fmt.Errorf("sync topic publish: %w", ErrTopicClosed)
 	}
 
-	t.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("sync topic publish: %w", err)
+	}
+
+	if err := t.lock(ctx, msg); err != nil {
+		return fmt.Errorf("sync topic publish: %w", err)
+	}
 	defer t.mu.Unlock()
 
-	t.subscribers = sequentialDelivery(msg, t.subscribers)
+	t.deliver(msg)
 
 	return nil
 }
 
+// lock acquires the lock in order to deliver msg. It gives up if ctx is done or if Shutdown gave up
+// waiting for publishes in progress, in which case msg is handed to the undelivered callback.
+func (t *SyncTopic[T]) lock(ctx context.Context, msg T) error {
+	if t.mu.TryLock() {
+		return nil
+	}
+
+	// Waiting for a sync.Mutex can't be abandoned so a helper go routine waits for it instead. The
+	// helper hands the lock over or, if the publish was abandoned in the meantime, gives it back.
+	locked := make(chan struct{})
+	abandon := make(chan struct{})
+
+	go func() {
+		t.mu.Lock()
+
+		select {
+		case locked <- struct{}{}:
+		case <-abandon:
+			t.mu.Unlock()
+		}
+	}()
+
+	var err error
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-t.abandoned:
+		t.options.TriggerUndelivered(msg)
+		err = ErrTopicClosed
+	}
+
+	close(abandon)
+
+	return err
+}
+
+// deliver hands msg to all subscribers. The lock must be held.
+func (t *SyncTopic[T]) deliver(msg T) {
+	t.subscribers = strategyDelivery(t.options.Delivery(), msg, t.subscribers)
+
+	if t.options.ReplayLatest() {
+		t.latest.set(msg)
+	}
+}
+
 // Subscribe adds a Subscriber func that will consume future published messages.
 func (t *SyncTopic[T]) Subscribe(fn Subscriber[T]) error {
-	if t.closed.Load() {
-		return fmt.Errorf("sync topic subscribe: %w", ErrTopicClosed)
+	_, err := t.SubscribeWithHandle(fn)
+	return err
+}
+
+// SubscribeWithHandle adds a Subscriber func that will consume future published messages and
+// returns a Subscription that can be used to remove it.
+func (t *SyncTopic[T]) SubscribeWithHandle(fn Subscriber[T]) (Subscription, error) {
+	s := newSubscription(fn, &t.options, t.unsubscribe)
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
 	}
 
+	return s, nil
+}
+
+// SubscribeErr adds an ErrorSubscriber func that will consume future published messages and returns
+// a Subscription that can be used to remove it. Failures are reported to the WithOnError handler.
+func (t *SyncTopic[T]) SubscribeErr(fn ErrorSubscriber[T]) (Subscription, error) {
+	s := newErrSubscription(fn, &t.options, t.unsubscribe)
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SubscribeClosable adds a ClosableSubscriber that will consume future published messages
+// and returns a Subscription that can be used to remove it. The subscriber is closed once removed.
+func (t *SyncTopic[T]) SubscribeClosable(sub ClosableSubscriber[T]) (Subscription, error) {
+	s := newClosableSubscription(sub, &t.options, t.unsubscribe)
+
+	if err := t.subscribe(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SubscribeGroup adds a Subscriber func as a member of the named consumer group and returns a
+// Subscription that can be used to remove it. Each message is delivered to only one member of the
+// group, in turns. A member that returns false leaves the group and the group is removed once it
+// has no members left.
+func (t *SyncTopic[T]) SubscribeGroup(name string, fn Subscriber[T]) (Subscription, error) {
+	return t.groups.join(name, fn, &t.options, t.subscribe, t.unsubscribe)
+}
+
+// SubscribeType adds a Subscriber func that will only consume future published messages whose
+// dynamic type is typ, or implements typ if it is an interface. It returns a Subscription that can
+// be used to remove it. See SubscribeAs for a type safe alternative.
+func (t *SyncTopic[T]) SubscribeType(typ reflect.Type, fn Subscriber[T]) (Subscription, error) {
+	return t.types.subscribe(typ, fn, &t.options, t.subscribe, t.unsubscribe, t.withLatest)
+}
+
+// SubscribeContext adds a Subscriber func that will consume future published messages until it
+// returns false or ctx is done, whichever happens first.
+func (t *SyncTopic[T]) SubscribeContext(ctx context.Context, fn Subscriber[T]) error {
+	s := newSubscription(fn, &t.options, t.unsubscribe)
+	s.bind(ctx)
+
+	return t.subscribe(s)
+}
+
+func (t *SyncTopic[T]) subscribe(s *subscription[T]) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	t.subscribers = append(t.subscribers, fn)
+	// Checking while holding the lock ensures no subscriber is added after Close released them.
+	if t.isClosed() {
+		s.detach() // never part of the topic so the subscriber is left alone
+		return fmt.Errorf("sync topic subscribe: %w", ErrTopicClosed)
+	}
+
+	// Replaying while holding the lock ensures no message is published in between.
+	if t.latest.replay(s) {
+		t.subscribers = addSubscription(t.subscribers, s)
+	} else {
+		s.release()
+	}
+
 	t.options.TriggerSubscribe()
 
 	return nil
 }
 
+// withLatest calls fn with the message to replay to new subscribers while holding the lock so that
+// no message is published in between.
+func (t *SyncTopic[T]) withLatest(fn func(*latest[T])) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isClosed() {
+		return fmt.Errorf("sync topic subscribe: %w", ErrTopicClosed)
+	}
+
+	fn(&t.latest)
+
+	return nil
+}
+
+func (t *SyncTopic[T]) unsubscribe(s *subscription[T]) {
+	t.withLock(func() {
+		t.subscribers = removeSubscription(t.subscribers, s)
+	})
+}
+
+// withLock runs fn while holding the lock, see withLock.
+func (t *SyncTopic[T]) withLock(fn func()) {
+	withLock(&t.mu, fn)
+}
+
 func (t *SyncTopic[T]) SetOptions(opts ...TopicOption) {
 	t.options.Apply(opts...)
 }
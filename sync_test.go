@@ -1,8 +1,11 @@
 package gubgub
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -101,3 +104,230 @@ func TestSyncTopic_ClosedTopicError(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncTopic_ParallelDelivery(t *testing.T) {
+	const subCount = 10
+
+	topic := NewSyncTopic[int](WithDelivery(ParallelDelivery(subCount)))
+
+	var (
+		wg       sync.WaitGroup
+		feedback atomic.Int64
+	)
+
+	wg.Add(subCount) // every subscriber must be running before any of them is allowed to return
+
+	for range subCount {
+		err := topic.Subscribe(Once(func(i int) {
+			wg.Done()
+			wg.Wait()
+			feedback.Add(1)
+		}))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, topic.Publish(1))
+	assert.Equal(t, int64(subCount), feedback.Load())
+
+	require.NoError(t, topic.Publish(2))
+	assert.Equal(t, int64(subCount), feedback.Load(), "expected all subscribers to have unsubscribed")
+}
+
+func TestSyncTopic_PublishContext(t *testing.T) {
+	topic := NewSyncTopic[int]()
+	t.Cleanup(topic.Close)
+
+	release := make(chan struct{})
+	delivered := make(chan int, 2)
+
+	started := make(chan struct{}, 1)
+
+	err := topic.Subscribe(Forever(func(i int) {
+		started <- struct{}{}
+		<-release // slow subscriber
+		delivered <- i
+	}))
+	require.NoError(t, err)
+
+	// The first publish can't be interrupted once delivery started.
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	first := make(chan error)
+
+	go func() {
+		first <- topic.PublishContext(firstCtx, 1)
+	}()
+
+	<-started
+	cancelFirst()
+
+	// The second publish gives up because delivery can't start.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, topic.PublishContext(ctx, 2), context.DeadlineExceeded)
+
+	close(release)
+
+	require.NoError(t, <-first, "expected delivery in progress to complete")
+	require.NoError(t, topic.PublishContext(context.Background(), 3))
+
+	assert.Equal(t, 1, <-delivered)
+	assert.Equal(t, 3, <-delivered)
+}
+
+func TestSyncTopic_Shutdown(t *testing.T) {
+	topic := NewSyncTopic[int]()
+
+	release := make(chan struct{})
+	feedback := make(chan int, 1)
+
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		<-release
+		feedback <- i
+	})))
+
+	published := make(chan error)
+	go func() {
+		published <- topic.Publish(1)
+	}()
+
+	// Wait for the publish to be in progress.
+	for topic.state.Load()>>1 < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- topic.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatalf("expected shutdown to wait for the publish in progress")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+
+	require.NoError(t, <-published)
+	require.NoError(t, <-shutdown)
+	assert.Equal(t, 1, <-feedback)
+
+	assert.ErrorIs(t, topic.Publish(2), ErrTopicClosed)
+}
+
+func TestSyncTopic_ShutdownDeadline(t *testing.T) {
+	undelivered := make(chan any, 1)
+
+	topic := NewSyncTopic[int](WithOnUndelivered(func(msg any) {
+		undelivered <- msg
+	}))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		close(started)
+		<-release // wedged subscriber
+	})))
+
+	go func() {
+		_ = topic.Publish(1)
+	}()
+
+	<-started
+
+	waiting := make(chan error)
+	go func() {
+		waiting <- topic.Publish(2)
+	}()
+
+	// Wait for the second publish to be in progress.
+	for topic.state.Load()>>1 < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := topic.Shutdown(ctx)
+
+	var undeliveredErr *UndeliveredError
+	require.ErrorAs(t, err, &undeliveredErr)
+	assert.Equal(t, 2, undeliveredErr.Undelivered)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.ErrorIs(t, <-waiting, ErrTopicClosed, "expected waiting publish to be abandoned")
+	assert.Equal(t, 2, <-undelivered)
+}
+
+func TestSyncTopic_ShutdownDuringCancelledPublish(t *testing.T) {
+	topic := NewSyncTopic[int]()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	require.NoError(t, topic.Subscribe(Forever(func(i int) {
+		close(started)
+		<-release // wedged subscriber
+	})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = topic.PublishContext(ctx, 1)
+	}()
+
+	<-started
+	cancel() // the publish is still in progress even though its context is done
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shutdownCancel()
+
+	var undeliveredErr *UndeliveredError
+	require.ErrorAs(t, topic.Shutdown(shutdownCtx), &undeliveredErr)
+	assert.Equal(t, 1, undeliveredErr.Undelivered)
+}
+
+func TestSyncTopic_ShutdownWedgedClose(t *testing.T) {
+	topic := NewSyncTopic[int]()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := topic.SubscribeClosable(closableFunc[int]{
+		receive: NoOp[int](),
+		close:   func() { <-release }, // never returns in time
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- topic.Shutdown(ctx)
+	}()
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case err := <-shutdown:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-timeout.C:
+		t.Fatalf("expected shutdown to give up once its context is done")
+	}
+}
+
+func TestSyncTopic_ShutdownReleasesSubscribers(t *testing.T) {
+	topic := NewSyncTopic[int]()
+
+	sub, err := topic.SubscribeWithHandle(NoOp[int]())
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(1))
+	require.NoError(t, topic.Shutdown(context.Background()))
+
+	assertClosed(t, sub.Done())
+}
@@ -0,0 +1,28 @@
+package gubgub
+
+// testTopic is a Topic that also implements the opt-in interfaces every kind of topic supports.
+type testTopic[T any] interface {
+	Topic[T]
+	GroupSubscribable[T]
+	TypeSubscribable[T]
+}
+
+// topicCase creates one kind of topic so that the same test can run against every kind of topic.
+type topicCase[T any] struct {
+	name     string
+	newTopic func(...TopicOption) testTopic[T]
+}
+
+// topicCases returns a topicCase for each kind of topic implementing Topic.
+func topicCases[T any]() []topicCase[T] {
+	return []topicCase[T]{
+		{
+			name:     "sync topic",
+			newTopic: func(opts ...TopicOption) testTopic[T] { return NewSyncTopic[T](opts...) },
+		},
+		{
+			name:     "async topic",
+			newTopic: func(opts ...TopicOption) testTopic[T] { return NewAsyncTopic[T](opts...) },
+		},
+	}
+}
@@ -0,0 +1,259 @@
+package gubgub
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// SubscribeAs subscribes fn to the messages of topic whose dynamic type is S. If S is an interface
+// then fn gets every message implementing it. This is mostly useful for topics whose message type T
+// is an interface: the topic keeps an index of these subscribers by type so fn is not even called
+// for messages of other types. Just like a Subscriber, fn unsubscribes by returning false.
+func SubscribeAs[T, S any](topic TypeSubscribable[T], fn func(S) bool) (Subscription, error) {
+	return topic.SubscribeType(reflect.TypeFor[S](), func(msg T) bool {
+		return fn(any(msg).(S))
+	})
+}
+
+// typeRoutes lazily subscribes a typeRouter to a topic. The zero value is ready to use.
+type typeRoutes[T any] struct {
+	mu     sync.Mutex
+	router *typeRouter[T]
+}
+
+// subscribe adds fn as a subscriber for messages of type typ. The router is created and registered in
+// the topic with subscribe if it doesn't exist yet. The subscriber is then added with withLatest so
+// that the latest message of the topic is replayed to it, if its type matches, with no message
+// delivered in between.
+func (tr *typeRoutes[T]) subscribe(
+	typ reflect.Type,
+	fn Subscriber[T],
+	options *TopicOptions,
+	subscribe func(*subscription[T]) error,
+	unsubscribe func(*subscription[T]),
+	withLatest func(func(*latest[T])) error,
+) (Subscription, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if tr.router == nil {
+		r := newTypeRouter(options, unsubscribe)
+		if err := subscribe(r.sub); err != nil {
+			return nil, err
+		}
+
+		tr.router = r
+	}
+
+	return tr.router.add(typ, fn, withLatest)
+}
+
+// typeRouter is subscribed to the topic like any other subscriber but hands each message only to the
+// subscribers registered for its dynamic type.
+type typeRouter[T any] struct {
+	sub     *subscription[T] // the subscription of the router itself in the topic
+	options *TopicOptions
+
+	mu     sync.RWMutex
+	byType map[reflect.Type][]*subscription[T] // subscribers by the type they asked for
+	routes map[reflect.Type][]*subscription[T] // subscribers by dynamic type of the messages seen
+	closed bool
+
+	delivering sync.Mutex // held while a message is delivered to the subscribers
+}
+
+func newTypeRouter[T any](options *TopicOptions, unsubscribe func(*subscription[T])) *typeRouter[T] {
+	r := &typeRouter[T]{
+		options: options,
+		byType:  make(map[reflect.Type][]*subscription[T]),
+		routes:  make(map[reflect.Type][]*subscription[T]),
+	}
+	r.sub = newClosableSubscription[T](r, options, unsubscribe)
+
+	return r
+}
+
+// Receive delivers msg to the subscribers of its dynamic type according to the delivery strategy of
+// the topic.
+func (r *typeRouter[T]) Receive(msg T) bool {
+	subscribers := r.route(reflect.TypeOf(any(msg)))
+	if len(subscribers) == 0 {
+		return true
+	}
+
+	// Subscribers that return false are only unsubscribed once the delivery is done, see remove.
+	var stopped []*subscription[T]
+
+	r.delivering.Lock()
+
+	switch strategy := r.options.Delivery().(type) {
+	case nil, sequentialStrategy:
+		for i := 0; i < len(subscribers); {
+			i = routeFrom(i, subscribers, msg, &stopped)
+		}
+
+	default:
+		var mu sync.Mutex
+		strategy.Deliver(len(subscribers), func(i int) {
+			if !subscribers[i].safeDeliver(msg) {
+				mu.Lock()
+				stopped = append(stopped, subscribers[i])
+				mu.Unlock()
+			}
+		})
+	}
+
+	r.delivering.Unlock()
+
+	for _, s := range stopped {
+		s.Unsubscribe()
+	}
+
+	return true
+}
+
+// routeFrom delivers msg to the subscribers starting at index i and appends the ones that returned
+// false to stopped. Like sequentialDelivery, panics are recovered once for the whole loop: the index
+// to resume from is returned after a subscriber panics. Subscribers are never removed from the slice
+// here since it is shared.
+func routeFrom[T any](i int, subscribers []*subscription[T], msg T, stopped *[]*subscription[T]) (next int) {
+	defer func() {
+		if r := recover(); r != nil {
+			if s := subscribers[next]; !s.recovered(r, msg) {
+				*stopped = append(*stopped, s)
+			}
+			next++
+		}
+	}()
+
+	for next = i; next < len(subscribers); next++ {
+		if s := subscribers[next]; !s.deliver(msg) {
+			*stopped = append(*stopped, s)
+		}
+	}
+
+	return next
+}
+
+// Close removes all subscribers. This is called once the router is removed from the topic.
+func (r *typeRouter[T]) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	for typ, subscribers := range r.byType {
+		releaseSubscriptions(subscribers)
+		delete(r.byType, typ)
+	}
+
+	clear(r.routes)
+}
+
+// route returns the subscribers for messages of the given dynamic type. The result is cached until
+// subscribers change. The returned slice must not be modified.
+func (r *typeRouter[T]) route(dynamic reflect.Type) []*subscription[T] {
+	if dynamic == nil { // nil interface value
+		return nil
+	}
+
+	r.mu.RLock()
+	subscribers, ok := r.routes[dynamic]
+	r.mu.RUnlock()
+
+	if ok {
+		return subscribers
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for typ, subs := range r.byType {
+		if matches(typ, dynamic) {
+			subscribers = append(subscribers, subs...)
+		}
+	}
+
+	r.routes[dynamic] = subscribers
+
+	return subscribers
+}
+
+// add registers fn as a subscriber for messages of type typ. The latest message is replayed to fn
+// right before it is registered, from withLatest.
+func (r *typeRouter[T]) add(typ reflect.Type, fn Subscriber[T], withLatest func(func(*latest[T])) error) (Subscription, error) {
+	r.mu.RLock()
+	closed := r.closed
+	r.mu.RUnlock()
+
+	if closed {
+		return nil, fmt.Errorf("subscribe type: %w", ErrTopicClosed)
+	}
+
+	s := newSubscription(fn, r.options, func(s *subscription[T]) {
+		r.remove(typ, s)
+	})
+
+	err := withLatest(func(l *latest[T]) {
+		if l.ok && matches(typ, reflect.TypeOf(any(l.msg))) && !s.safeDeliver(l.msg) {
+			s.release()
+			return
+		}
+
+		r.register(typ, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// register adds s to the subscribers for messages of type typ unless it unsubscribed in the
+// meantime or the router was closed.
+func (r *typeRouter[T]) register(typ reflect.Type, s *subscription[T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || s.unsubscribed.Load() {
+		s.release()
+		return
+	}
+
+	// Slices are never modified in place since they might be in use by Receive.
+	r.byType[typ] = append(slices.Clip(r.byType[typ]), s)
+	clear(r.routes)
+}
+
+// remove unregisters s as a subscriber for messages of type typ. Just like for subscribers of a
+// topic, s is only removed once the message being delivered, if any, is done.
+func (r *typeRouter[T]) remove(typ reflect.Type, s *subscription[T]) {
+	withLock(&r.delivering, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		subscribers := r.byType[typ]
+
+		i := slices.Index(subscribers, s)
+		if i < 0 {
+			return // already removed by Close or not registered yet, see register
+		}
+
+		if len(subscribers) == 1 {
+			delete(r.byType, typ)
+		} else {
+			r.byType[typ] = slices.Delete(slices.Clone(subscribers), i, i+1)
+		}
+
+		clear(r.routes)
+
+		s.release()
+	})
+}
+
+// matches returns true if messages of the dynamic type go to the subscribers of typ.
+func matches(typ, dynamic reflect.Type) bool {
+	return typ == dynamic || (typ.Kind() == reflect.Interface && dynamic != nil && dynamic.Implements(typ))
+}
@@ -0,0 +1,28 @@
+package gubgub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkSubscribeAs(b *testing.B) {
+	topic := NewSyncTopic[domainEvent]()
+	defer topic.Close()
+
+	for range 100 {
+		_, err := SubscribeAs(topic, func(e accountClosed) bool { return true })
+		require.NoError(b, err)
+	}
+
+	_, err := SubscribeAs(topic, func(e accountOpened) bool { return true })
+	require.NoError(b, err)
+
+	msg := accountOpened{Account: "a"}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_ = topic.Publish(msg)
+	}
+}
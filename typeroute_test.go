@@ -0,0 +1,160 @@
+package gubgub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type domainEvent interface {
+	Aggregate() string
+}
+
+type accountOpened struct {
+	Account string
+}
+
+func (e accountOpened) Aggregate() string { return e.Account }
+
+type accountClosed struct {
+	Account string
+}
+
+func (e accountClosed) Aggregate() string { return e.Account }
+
+func (e accountClosed) Final() bool { return true }
+
+func TestSubscribeAs(t *testing.T) {
+	testCases := append(topicCases[domainEvent](), topicCase[domainEvent]{
+		name: "parallel delivery",
+		newTopic: func(opts ...TopicOption) testTopic[domainEvent] {
+			return NewSyncTopic[domainEvent](append(opts, WithDelivery(ParallelDelivery(4)))...)
+		},
+	})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			topic := tc.newTopic()
+
+			var (
+				opened []accountOpened
+				closed []accountClosed
+				final  []string
+				all    []domainEvent
+			)
+
+			_, err := SubscribeAs(topic, func(e accountOpened) bool {
+				opened = append(opened, e)
+				return true
+			})
+			require.NoError(t, err)
+
+			_, err = SubscribeAs(topic, func(e accountClosed) bool {
+				closed = append(closed, e)
+				return true
+			})
+			require.NoError(t, err)
+
+			_, err = SubscribeAs(topic, func(e interface{ Final() bool }) bool {
+				final = append(final, e.(domainEvent).Aggregate())
+				return true
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, topic.Subscribe(Forever(func(e domainEvent) {
+				all = append(all, e)
+			})))
+
+			require.NoError(t, topic.Publish(accountOpened{Account: "a"}))
+			require.NoError(t, topic.Publish(accountClosed{Account: "a"}))
+			require.NoError(t, topic.Publish(accountOpened{Account: "b"}))
+			require.NoError(t, topic.Publish(nil))
+
+			topic.Close()
+
+			assert.Equal(t, []accountOpened{{Account: "a"}, {Account: "b"}}, opened)
+			assert.Equal(t, []accountClosed{{Account: "a"}}, closed)
+			assert.Equal(t, []string{"a"}, final)
+			assert.Len(t, all, 4)
+		})
+	}
+}
+
+func TestSubscribeAs_Unsubscribe(t *testing.T) {
+	topic := NewSyncTopic[domainEvent]()
+
+	var calls int
+	once, err := SubscribeAs(topic, func(e accountOpened) bool {
+		calls++
+		return false
+	})
+	require.NoError(t, err)
+
+	var closedCalls int
+	handle, err := SubscribeAs(topic, func(e accountClosed) bool {
+		closedCalls++
+		return true
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, topic.Publish(accountOpened{}))
+	require.NoError(t, topic.Publish(accountOpened{}))
+	assert.Equal(t, 1, calls)
+	assertClosed(t, once.Done())
+
+	handle.Unsubscribe()
+	require.NoError(t, topic.Publish(accountClosed{}))
+	assert.Zero(t, closedCalls)
+	assertClosed(t, handle.Done())
+
+	topic.Close()
+
+	_, err = SubscribeAs(topic, func(e accountOpened) bool { return true })
+	assert.ErrorIs(t, err, ErrTopicClosed)
+}
+
+func TestSubscribeAs_UnsubscribeWhileDelivering(t *testing.T) {
+	topic := NewSyncTopic[domainEvent]()
+	defer topic.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	sub, err := SubscribeAs(topic, func(accountOpened) bool {
+		close(started)
+		<-release
+		return true
+	})
+	require.NoError(t, err)
+
+	go func() { _ = topic.Publish(accountOpened{}) }()
+	<-started
+
+	sub.Unsubscribe()
+
+	select {
+	case <-sub.Done():
+		t.Fatalf("expected handler to stay subscribed until the delivery returns")
+	default:
+	}
+
+	close(release)
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case <-sub.Done():
+	case <-timeout.C:
+		t.Fatalf("expected handler to be removed once the delivery returned")
+	}
+}
+
+func TestSubscribeAs_TopicClosed(t *testing.T) {
+	topic := NewAsyncTopic[domainEvent]()
+
+	sub, err := SubscribeAs(topic, func(e accountOpened) bool { return true })
+	require.NoError(t, err)
+
+	topic.Close()
+
+	assertClosed(t, sub.Done())
+}
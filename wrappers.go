@@ -1,5 +1,16 @@
 package gubgub
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // Forever wraps a subscriber that will never stop consuming messages.
 // This helps avoiding subscribers that always return TRUE.
 func Forever[T any](fn func(T)) Subscriber[T] {
@@ -32,77 +43,664 @@ func NoOp[T any]() Subscriber[T] {
 //
 // IMPORTANT: messages are considered delivered even it they are still in the buffer which means
 // that while the wrapper subscriber is covered by the publishing promise the inner subscriber is
-// is not.
+// is not. The topic can't close this subscriber either: its go routine keeps running after the
+// topic is closed until the inner subscriber returns false. Use NewBuffered and SubscribeClosable
+// instead so that closing the topic flushes the buffer and stops the go routine.
 //
 // Message average processing rate must still be higher than the average message publishing rate
 // otherwise it will eventually lead to memory issues. You will need to find a better strategy to
 // deal with such scenario.
 func Buffered[T any](subscriber Subscriber[T]) Subscriber[T] {
-	unsubscribe := make(chan struct{}) // closed by the worker
-	ready := make(chan struct{})       // closed by the worker
-	messages := make(chan T)           // closed by the forwarder
-	work := make(chan T)               // closed by the middleman
-
-	// Worker calls the actual subscriber. It notifies the middleman that it's ready for the next
-	// message via the ready channel and then reads from the work channel.
-	go func() {
-		for w := range work {
-			if !subscriber(w) {
-				close(unsubscribe)
-				close(ready)
-				return
+	return NewBuffered(subscriber).Receive
+}
+
+// BufferPolicy decides what happens when a message is received by a BufferedN subscriber whose
+// buffer is full.
+type BufferPolicy int
+
+const (
+	// BufferBlock blocks the publisher until there is room in the buffer.
+	BufferBlock BufferPolicy = iota
+
+	// BufferDropNewest discards the message being delivered.
+	BufferDropNewest
+
+	// BufferDropOldest discards the oldest buffered message to make room for the message being
+	// delivered.
+	BufferDropOldest
+
+	// BufferUnsubscribe discards the message being delivered and unsubscribes. Messages already
+	// buffered are still processed.
+	BufferUnsubscribe
+)
+
+// BufferedN returns a subscriber that buffers up to size messages if they can't be delivered
+// immediately. Unlike Buffered, memory usage is bounded: messages are kept in a ring buffer and the
+// BufferPolicy decides what happens when it is full.
+//
+// Discarded messages are handed to onDrop, if not nil. So are messages left in the buffer once the
+// inner subscriber unsubscribes.
+//
+// IMPORTANT: just like with Buffered, messages are considered delivered while still in the buffer
+// and the go routine keeps running after the topic is closed. Use NewBufferedN and SubscribeClosable
+// to avoid that.
+func BufferedN[T any](size int, policy BufferPolicy, subscriber Subscriber[T], onDrop func(msg T)) Subscriber[T] {
+	return NewBufferedN(size, policy, subscriber, onDrop).Receive
+}
+
+// BufferedSubscriber is a ClosableSubscriber that buffers messages for the inner subscriber which is
+// called from a separate go routine. Closing it flushes the buffer to the inner subscriber and waits
+// for it to be done.
+type BufferedSubscriber[T any] struct {
+	subscriber Subscriber[T]
+	onDrop     func(msg T)
+
+	mu       sync.Mutex
+	messages ring[T]
+	size     int // zero means unbounded
+	policy   BufferPolicy
+	stopped  bool          // true once the wrapper or the inner subscriber unsubscribed
+	space    chan struct{} // closed when a message leaves the buffer, if anyone is waiting for room
+	ready    chan struct{} // signals the worker that there are messages in the buffer
+	done     chan struct{} // closed once the worker quits
+}
+
+// NewBuffered creates an unbounded BufferedSubscriber. See Buffered.
+func NewBuffered[T any](subscriber Subscriber[T]) *BufferedSubscriber[T] {
+	return newBufferedSubscriber(0, BufferBlock, subscriber, nil)
+}
+
+// NewBufferedN creates a BufferedSubscriber that buffers up to size messages. See BufferedN.
+func NewBufferedN[T any](size int, policy BufferPolicy, subscriber Subscriber[T], onDrop func(msg T)) *BufferedSubscriber[T] {
+	return newBufferedSubscriber(max(size, 1), policy, subscriber, onDrop)
+}
+
+func newBufferedSubscriber[T any](size int, policy BufferPolicy, subscriber Subscriber[T], onDrop func(msg T)) *BufferedSubscriber[T] {
+	b := &BufferedSubscriber[T]{
+		subscriber: subscriber,
+		onDrop:     onDrop,
+		size:       size,
+		policy:     policy,
+		ready:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	go b.work()
+
+	return b
+}
+
+// Receive buffers msg for the inner subscriber and returns false once the subscriber should
+// unsubscribe.
+func (b *BufferedSubscriber[T]) Receive(msg T) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for !b.stopped && b.size > 0 && b.messages.Len() >= b.size {
+		switch b.policy {
+		case BufferDropNewest:
+			b.drop(msg)
+			return true
+
+		case BufferDropOldest:
+			oldest, _ := b.messages.Pop()
+			b.drop(oldest)
+
+		case BufferUnsubscribe:
+			b.stopped = true
+			b.drop(msg)
+			b.signal() // so that the worker quits once the buffer is empty
+			return false
+
+		default:
+			if b.space == nil {
+				b.space = make(chan struct{})
 			}
-			ready <- struct{}{}
+			space := b.space
+
+			b.mu.Unlock()
+			<-space
+			b.mu.Lock()
 		}
-	}()
+	}
 
-	// Middleman that handles buffering. When the worker notifies that it is ready for the next
-	// message it will check if there is buffered messages and push the next one immediately or
-	// else push it when the next message arrives.
-	go func() {
-		defer close(work)
+	if b.stopped {
+		b.drop(msg)
+		return false
+	}
 
-		idling := true // so that the first message can go straight to the consumer
+	b.messages.Push(msg)
+	b.signal()
 
-		q := make([]T, 0, 1)
+	return true
+}
 
-		for {
-			select {
-			case msg, more := <-messages:
-				if !more {
-					return
-				}
+// Close stops accepting messages and waits for the inner subscriber to process the buffered ones.
+// Messages received afterwards are dropped. This is idempotent and thread safe.
+func (b *BufferedSubscriber[T]) Close() {
+	b.halt()
+	<-b.done
+}
 
-				if idling {
-					idling = false
-					work <- msg
-				} else {
-					q = append(q, msg)
-				}
+// halt stops accepting messages. The worker quits once it processed the buffered ones.
+func (b *BufferedSubscriber[T]) halt() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-			case _, more := <-ready:
-				if !more {
-					return
-				}
+	b.stopped = true
+	b.releaseSpace()
+	b.signal()
+}
 
-				if len(q) > 0 {
-					work <- q[0]
-					q = q[1:]
-				} else {
-					idling = true
-				}
+// signal wakes up the worker. The worker takes all buffered messages once woken up so a pending
+// signal is enough.
+func (b *BufferedSubscriber[T]) signal() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// work calls the inner subscriber for each buffered message until it unsubscribes or until the
+// wrapper unsubscribed and the buffer is empty.
+func (b *BufferedSubscriber[T]) work() {
+	defer close(b.done)
+
+	for range b.ready {
+		for {
+			msg, ok, stopped := b.next()
+			if stopped && !ok {
+				return
+			}
+
+			if !ok {
+				break
+			}
+
+			if !b.subscriber(msg) {
+				b.stop()
+				return
 			}
 		}
+	}
+}
+
+// next takes the oldest buffered message. Returns false if the buffer is empty. Also reports
+// whether the wrapper has unsubscribed.
+func (b *BufferedSubscriber[T]) next() (T, bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	msg, ok := b.messages.Pop()
+	if ok {
+		b.releaseSpace()
+	}
+
+	return msg, ok, b.stopped
+}
+
+// stop drops every buffered message and tells the worker to quit. This is used once the inner
+// subscriber unsubscribed.
+func (b *BufferedSubscriber[T]) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = true
+
+	for msg, ok := b.messages.Pop(); ok; msg, ok = b.messages.Pop() {
+		b.drop(msg)
+	}
+
+	b.releaseSpace()
+	b.signal()
+}
+
+// releaseSpace wakes up publishers waiting for room in the buffer. The lock must be held.
+func (b *BufferedSubscriber[T]) releaseSpace() {
+	if b.space != nil {
+		close(b.space)
+		b.space = nil
+	}
+}
+
+// drop hands a discarded message to the onDrop callback. The lock must be held.
+func (b *BufferedSubscriber[T]) drop(msg T) {
+	if b.onDrop != nil {
+		b.onDrop(msg)
+	}
+}
+
+// Concurrent returns a subscriber that processes messages on n go routines. Up to n messages wait in
+// a queue for a free worker; once the queue is full, receiving blocks until a worker takes the next
+// message. This allows one heavy subscriber to consume messages in parallel without switching the
+// whole topic to ParallelDelivery.
+//
+// Messages are no longer processed in order. As soon as the inner subscriber returns false on any
+// worker, the wrapper unsubscribes and queued messages are discarded.
+//
+// IMPORTANT: messages are considered delivered while still in the queue and the workers keep running
+// after the topic is closed. Use NewConcurrent and SubscribeClosable to avoid that.
+func Concurrent[T any](n int, subscriber Subscriber[T]) Subscriber[T] {
+	return NewConcurrent(n, subscriber).Receive
+}
+
+// NewConcurrent creates a ConcurrentSubscriber that processes messages on n go routines. See
+// Concurrent.
+//
+// Close stops accepting messages and waits for the workers to process the queued ones. Subscribe it
+// with SubscribeClosable so that closing the topic does that for you.
+func NewConcurrent[T any](n int, subscriber Subscriber[T]) *ConcurrentSubscriber[T] {
+	n = max(n, 1)
+
+	c := &ConcurrentSubscriber[T]{
+		subscriber: subscriber,
+		queue:      make(chan T, n),
+		stop:       make(chan struct{}),
+		running:    n,
+	}
+	c.idle.L = &c.idleMu
+
+	for range n {
+		go c.work()
+	}
+
+	return c
+}
+
+// ConcurrentSubscriber is a ClosableSubscriber that processes messages on a pool of go routines.
+// See Concurrent.
+type ConcurrentSubscriber[T any] struct {
+	subscriber Subscriber[T]
+
+	mu     sync.RWMutex
+	closed bool // no more messages are accepted once true
+	queue  chan T
+
+	stop         chan struct{} // closed once closing or unsubscribing
+	stopOnce     sync.Once
+	unsubscribed atomic.Bool // true once the inner subscriber returned false
+
+	idleMu  sync.Mutex
+	idle    sync.Cond // signaled whenever pending or running drops to zero
+	pending int       // messages received but not processed yet
+	running int       // workers that didn't quit yet
+}
+
+// Receive queues msg for the workers and returns false once the subscriber should unsubscribe.
+func (c *ConcurrentSubscriber[T]) Receive(msg T) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed || c.unsubscribed.Load() {
+		return false
+	}
+
+	c.addPending(1)
+
+	select {
+	case c.queue <- msg:
+		return true
+	case <-c.stop:
+		c.addPending(-1)
+		return false
+	}
+}
+
+// Close stops accepting messages and waits for the workers to process the queued ones and quit. This
+// is idempotent and thread safe.
+func (c *ConcurrentSubscriber[T]) Close() {
+	// Once the lock is acquired no message is being queued and no message will ever be queued.
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.halt()
+	c.waitUntil(func() bool { return c.running == 0 })
+}
+
+// Wait blocks until every message received so far has been processed, without closing the
+// subscriber. It also returns once the workers quit because the inner subscriber returned false, in
+// which case queued messages are discarded.
+func (c *ConcurrentSubscriber[T]) Wait() {
+	c.waitUntil(func() bool { return c.pending == 0 || c.running == 0 })
+}
+
+// waitUntil blocks until done, which is called while holding idleMu, returns true.
+func (c *ConcurrentSubscriber[T]) waitUntil(done func() bool) {
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+
+	for !done() {
+		c.idle.Wait()
+	}
+}
+
+// addPending updates the number of messages waiting to be processed.
+func (c *ConcurrentSubscriber[T]) addPending(delta int) {
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+
+	c.pending += delta
+	if c.pending == 0 {
+		c.idle.Broadcast()
+	}
+}
+
+// halt tells the workers to quit once the queue is empty.
+func (c *ConcurrentSubscriber[T]) halt() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// work calls the inner subscriber for queued messages until told to stop and the queue is empty.
+func (c *ConcurrentSubscriber[T]) work() {
+	defer func() {
+		c.idleMu.Lock()
+		defer c.idleMu.Unlock()
+
+		c.running--
+		if c.running == 0 {
+			c.idle.Broadcast()
+		}
 	}()
 
-	// forwarder just sends messages to the middleman or quits.
-	return func(msg T) bool {
+	for {
 		select {
-		case messages <- msg:
-			return true
-		case <-unsubscribe:
-			close(messages)
-			return false
+		case msg := <-c.queue:
+			if !c.process(msg) {
+				return
+			}
+
+		case <-c.stop:
+			for {
+				select {
+				case msg := <-c.queue:
+					if !c.process(msg) {
+						return
+					}
+				default:
+					return
+				}
+			}
 		}
 	}
 }
+
+// process calls the inner subscriber unless it already unsubscribed. Returns false if the worker
+// should quit.
+func (c *ConcurrentSubscriber[T]) process(msg T) bool {
+	defer c.addPending(-1)
+
+	if c.unsubscribed.Load() {
+		return false
+	}
+
+	if !c.subscriber(msg) {
+		c.unsubscribed.Store(true)
+		c.halt()
+		return false
+	}
+
+	return true
+}
+
+// Partitioned returns a subscriber that spreads messages over n partitions according to their key.
+// Each partition processes its messages one at a time, in order, on its own go routine. Messages with
+// the same key always land on the same partition so they are processed in the order they were
+// received while messages with different keys are likely to be processed in parallel.
+//
+// Each partition buffers up to size messages just like BufferedN so a busy partition doesn't hold
+// back the others, and policy decides what happens once the buffer of a partition is full.
+// Discarded messages are handed to onDrop, if not nil. As soon as the inner subscriber returns false
+// on any partition, or a partition unsubscribes because its buffer is full, the wrapper unsubscribes
+// and buffered messages are discarded.
+//
+// IMPORTANT: messages are considered delivered while still in a buffer and the partitions keep
+// running after the topic is closed. Use NewPartitioned and SubscribeClosable to avoid that.
+func Partitioned[T any, K comparable](
+	keyFn func(T) K,
+	n, size int,
+	policy BufferPolicy,
+	subscriber Subscriber[T],
+	onDrop func(msg T),
+) Subscriber[T] {
+	return NewPartitioned(keyFn, n, size, policy, subscriber, onDrop).Receive
+}
+
+// NewPartitioned creates a PartitionedSubscriber with n partitions buffering up to size messages each.
+// See Partitioned.
+//
+// Close stops accepting messages and waits for every partition to process its buffered messages.
+// Subscribe it with SubscribeClosable so that closing the topic does that for you.
+func NewPartitioned[T any, K comparable](
+	keyFn func(T) K,
+	n, size int,
+	policy BufferPolicy,
+	subscriber Subscriber[T],
+	onDrop func(msg T),
+) *PartitionedSubscriber[T] {
+	n = max(n, 1)
+	seed := maphash.MakeSeed()
+
+	p := &PartitionedSubscriber[T]{
+		subscriber: subscriber,
+		partition: func(msg T) int {
+			return int(maphash.Comparable(seed, keyFn(msg)) % uint64(n))
+		},
+		partitions: make([]*BufferedSubscriber[T], n),
+	}
+
+	for i := range p.partitions {
+		p.partitions[i] = NewBufferedN(size, policy, p.process, onDrop)
+	}
+
+	return p
+}
+
+// PartitionedSubscriber is a ClosableSubscriber that processes messages on key based partitions.
+// See Partitioned.
+type PartitionedSubscriber[T any] struct {
+	subscriber   Subscriber[T]
+	partition    func(msg T) int
+	partitions   []*BufferedSubscriber[T]
+	unsubscribed atomic.Bool // true once the inner subscriber returned false
+}
+
+// Receive hands msg to its partition and returns false once the subscriber should unsubscribe.
+func (p *PartitionedSubscriber[T]) Receive(msg T) bool {
+	if p.unsubscribed.Load() {
+		return false
+	}
+
+	if p.partitions[p.partition(msg)].Receive(msg) {
+		return true
+	}
+
+	// The buffer of the partition is full and the policy is to unsubscribe. The other partitions
+	// still process the messages they have but stop accepting new ones.
+	for _, partition := range p.partitions {
+		partition.halt()
+	}
+
+	return false
+}
+
+// Close stops accepting messages and waits for every partition to process its buffered messages.
+// This is idempotent and thread safe.
+func (p *PartitionedSubscriber[T]) Close() {
+	for _, partition := range p.partitions {
+		partition.Close()
+	}
+}
+
+// process calls the inner subscriber on behalf of a partition. Once the inner subscriber returns
+// false, every partition is stopped.
+func (p *PartitionedSubscriber[T]) process(msg T) bool {
+	if p.unsubscribed.Load() {
+		return false
+	}
+
+	if !p.subscriber(msg) {
+		p.unsubscribed.Store(true)
+
+		for _, partition := range p.partitions {
+			partition.stop()
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// RetryOptions holds the options of the Retry wrapper.
+type RetryOptions struct {
+	maxAttempts       int
+	initialBackoff    time.Duration
+	maxBackoff        time.Duration
+	backoffMultiplier float64
+	jitter            float64
+	onGiveUp          func(msg any, err error, attempts int)
+	ctx               context.Context
+}
+
+type RetryOption func(*RetryOptions)
+
+// WithMaxAttempts sets how many times a message is processed before giving up, including the first
+// attempt. Defaults to 3.
+func WithMaxAttempts(n int) RetryOption {
+	return func(opts *RetryOptions) {
+		opts.maxAttempts = max(n, 1)
+	}
+}
+
+// WithBackoff sets how long to wait before the first retry and the maximum wait between retries.
+// Defaults to 100ms and 10s.
+func WithBackoff(initial, maximum time.Duration) RetryOption {
+	return func(opts *RetryOptions) {
+		opts.initialBackoff = initial
+		opts.maxBackoff = max(initial, maximum)
+	}
+}
+
+// WithBackoffMultiplier sets how much the wait grows after each retry. Defaults to 2.
+func WithBackoffMultiplier(multiplier float64) RetryOption {
+	return func(opts *RetryOptions) {
+		opts.backoffMultiplier = max(multiplier, 1)
+	}
+}
+
+// WithJitter randomizes each wait by up to the given fraction of it (between 0 and 1) so that many
+// subscribers failing at once don't retry in lockstep. Defaults to 0.2 (±20%).
+func WithJitter(fraction float64) RetryOption {
+	return func(opts *RetryOptions) {
+		opts.jitter = min(max(fraction, 0), 1)
+	}
+}
+
+// WithOnGiveUp registers a func to be called when a message is given up on, with the message, the
+// last error and how many attempts were made.
+func WithOnGiveUp(fn func(msg any, err error, attempts int)) RetryOption {
+	return func(opts *RetryOptions) {
+		opts.onGiveUp = fn
+	}
+}
+
+// WithRetryContext stops waiting between attempts once ctx is done, in which case the message is
+// given up on right away. Cancel ctx upon shutdown so that the remaining backoff doesn't hold up
+// closing the topic.
+func WithRetryContext(ctx context.Context) RetryOption {
+	return func(opts *RetryOptions) {
+		opts.ctx = ctx
+	}
+}
+
+// RetryError is returned by a Retry subscriber once it gives up on a message.
+type RetryError struct {
+	// Err is the error returned by the last attempt.
+	Err error
+
+	attempts int
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("gave up after %d attempts: %v", e.attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Attempts returns how many times the message was processed before giving up.
+func (e *RetryError) Attempts() int {
+	return e.attempts
+}
+
+// Retry wraps an error returning subscriber so that each message that fails to be processed is
+// retried with an exponential backoff. Once all attempts fail, the give up callback is called and
+// a RetryError wrapping the last error is returned. Returning ErrUnsubscribe is never retried.
+//
+// IMPORTANT: the wrapper waits between attempts in the go routine delivering the message. Delivery
+// to other subscribers might be delayed unless the topic delivers messages in parallel. Closing the
+// topic waits for the whole retry schedule too, even past the deadline of Shutdown, unless the
+// wait is cut short with WithRetryContext.
+func Retry[T any](subscriber ErrorSubscriber[T], opts ...RetryOption) ErrorSubscriber[T] {
+	options := RetryOptions{
+		maxAttempts:       3,
+		initialBackoff:    100 * time.Millisecond,
+		maxBackoff:        10 * time.Second,
+		backoffMultiplier: 2,
+		jitter:            0.2,
+		ctx:               context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	giveUp := func(msg T, err error, attempts int) error {
+		if options.onGiveUp != nil {
+			options.onGiveUp(msg, err, attempts)
+		}
+
+		return &RetryError{Err: err, attempts: attempts}
+	}
+
+	return func(msg T) error {
+		backoff := options.initialBackoff
+
+		for attempt := 1; ; attempt++ {
+			err := subscriber(msg)
+			if err == nil || errors.Is(err, ErrUnsubscribe) {
+				return err
+			}
+
+			if attempt >= options.maxAttempts {
+				return giveUp(msg, err, attempt)
+			}
+
+			timer := time.NewTimer(withJitter(backoff, options.jitter))
+
+			select {
+			case <-timer.C:
+			case <-options.ctx.Done():
+				timer.Stop()
+				return giveUp(msg, err, attempt)
+			}
+
+			backoff = min(time.Duration(float64(backoff)*options.backoffMultiplier), options.maxBackoff)
+		}
+	}
+}
+
+// withJitter randomly changes d by up to the given fraction of it.
+func withJitter(d time.Duration, fraction float64) time.Duration {
+	if fraction == 0 || d <= 0 {
+		return d
+	}
+
+	delta := float64(d) * fraction * (2*rand.Float64() - 1)
+
+	return d + time.Duration(delta)
+}
@@ -1,10 +1,15 @@
 package gubgub
 
 import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBuffered_Once(t *testing.T) {
@@ -53,3 +58,491 @@ func TestBuffered_Forever(t *testing.T) {
 		}
 	}
 }
+
+func TestBufferedSubscriber_Close(t *testing.T) {
+	var received []int
+
+	b := NewBuffered(Forever(func(i int) {
+		time.Sleep(time.Millisecond) // slow enough for messages to pile up in the buffer
+		received = append(received, i)
+	}))
+
+	for i := range 5 {
+		assert.True(t, b.Receive(i))
+	}
+
+	b.Close()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, received)
+	assert.False(t, b.Receive(5))
+
+	b.Close() // idempotent
+}
+
+func TestBufferedN(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      BufferPolicy
+		wantResults []bool
+		wantDropped []int
+		wantHandled []int
+	}{
+		{
+			name:        "drop newest",
+			policy:      BufferDropNewest,
+			wantResults: []bool{true, true, true, true, true},
+			wantDropped: []int{3, 4},
+			wantHandled: []int{0, 1, 2},
+		},
+		{
+			name:        "drop oldest",
+			policy:      BufferDropOldest,
+			wantResults: []bool{true, true, true, true, true},
+			wantDropped: []int{1, 2},
+			wantHandled: []int{0, 3, 4},
+		},
+		{
+			name:        "unsubscribe",
+			policy:      BufferUnsubscribe,
+			wantResults: []bool{true, true, true, false, false},
+			wantDropped: []int{3, 4},
+			wantHandled: []int{0, 1, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				dropped []int
+				handled = make(chan int, 5)
+				started = make(chan struct{})
+				release = make(chan struct{})
+			)
+
+			s := BufferedN(2, tc.policy, Forever(func(i int) {
+				if i == 0 {
+					close(started)
+					<-release // hold the worker so that the buffer fills up
+				}
+				handled <- i
+			}), func(i int) {
+				dropped = append(dropped, i)
+			})
+
+			assert.True(t, s(0))
+			<-started
+
+			var results []bool
+			for i := 1; i < 5; i++ {
+				results = append(results, s(i))
+			}
+
+			assert.Equal(t, tc.wantResults, append([]bool{true}, results...))
+			assert.Equal(t, tc.wantDropped, dropped)
+
+			close(release)
+
+			timeout := testTimer(t, time.Second)
+
+			var got []int
+			for len(got) < len(tc.wantHandled) {
+				select {
+				case i := <-handled:
+					got = append(got, i)
+
+				case <-timeout.C:
+					t.Fatalf("expected %d handled messages by now", len(tc.wantHandled))
+				}
+			}
+
+			assert.Equal(t, tc.wantHandled, got)
+		})
+	}
+}
+
+func TestBufferedN_Block(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan int, 3)
+
+	s := BufferedN(1, BufferBlock, Forever(func(i int) {
+		<-release
+		handled <- i
+	}), nil)
+
+	assert.True(t, s(0)) // taken by the worker
+	assert.True(t, s(1)) // buffered
+
+	blocked := make(chan bool)
+	go func() {
+		blocked <- s(2)
+	}()
+
+	select {
+	case <-blocked:
+		t.Fatalf("expected publishing to a full buffer to block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case ok := <-blocked:
+		assert.True(t, ok)
+	case <-timeout.C:
+		t.Fatalf("expected publishing to be unblocked by now")
+	}
+
+	for want := range 3 {
+		select {
+		case i := <-handled:
+			assert.Equal(t, want, i)
+		case <-timeout.C:
+			t.Fatalf("expected 3 handled messages by now")
+		}
+	}
+}
+
+func TestBufferedN_InnerUnsubscribe(t *testing.T) {
+	release := make(chan struct{})
+	dropped := make(chan int, 3)
+
+	s := BufferedN(3, BufferBlock, func(i int) bool {
+		<-release
+		return false
+	}, func(i int) {
+		dropped <- i
+	})
+
+	assert.True(t, s(0)) // taken by the worker
+	assert.True(t, s(1))
+	assert.True(t, s(2))
+
+	close(release)
+
+	timeout := testTimer(t, time.Second)
+
+	for _, want := range []int{1, 2} {
+		select {
+		case i := <-dropped:
+			assert.Equal(t, want, i)
+		case <-timeout.C:
+			t.Fatalf("expected buffered messages to be dropped by now")
+		}
+	}
+
+	assert.False(t, s(3))
+	assert.Equal(t, 3, <-dropped)
+}
+
+func TestConcurrent(t *testing.T) {
+	const workers = 4
+
+	var (
+		started = make(chan int, workers)
+		release = make(chan struct{})
+		mu      sync.Mutex
+		handled []int
+	)
+
+	c := NewConcurrent(workers, Forever(func(i int) {
+		started <- i
+		<-release
+
+		mu.Lock()
+		handled = append(handled, i)
+		mu.Unlock()
+	}))
+
+	for i := range 2 * workers { // fills the workers and then the queue
+		assert.True(t, c.Receive(i))
+	}
+
+	timeout := testTimer(t, time.Second)
+
+	for range workers {
+		select {
+		case <-started:
+		case <-timeout.C:
+			t.Fatalf("expected %d messages to be processed concurrently", workers)
+		}
+	}
+
+	close(release)
+	c.Close()
+
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, handled)
+	assert.False(t, c.Receive(8))
+}
+
+func TestConcurrent_Unsubscribe(t *testing.T) {
+	var calls atomic.Int32
+
+	c := NewConcurrent(2, func(i int) bool {
+		calls.Add(1)
+		return i != 0
+	})
+
+	assert.True(t, c.Receive(0))
+
+	c.Wait() // the inner subscriber unsubscribing stops every worker
+
+	assert.False(t, c.Receive(1))
+	assert.Equal(t, int32(1), calls.Load())
+
+	c.Close()
+}
+
+func TestConcurrent_Wait(t *testing.T) {
+	const msgCount = 20
+
+	var handled atomic.Int32
+
+	c := NewConcurrent(4, Forever(func(int) {
+		time.Sleep(time.Millisecond)
+		handled.Add(1)
+	}))
+	defer c.Close()
+
+	for i := range msgCount {
+		require.True(t, c.Receive(i))
+	}
+
+	c.Wait()
+
+	assert.Equal(t, int32(msgCount), handled.Load(), "expected Wait to drain the queue")
+	assert.True(t, c.Receive(msgCount), "expected the subscriber to keep accepting messages")
+}
+
+func TestConcurrent_Topic(t *testing.T) {
+	const msgCount = 100
+
+	topic := NewAsyncTopic[int]()
+
+	var received atomic.Int32
+
+	_, err := topic.SubscribeClosable(NewConcurrent(8, Forever(func(i int) {
+		received.Add(1)
+	})))
+	require.NoError(t, err)
+
+	for i := range msgCount {
+		require.NoError(t, topic.Publish(i))
+	}
+
+	topic.Close()
+
+	assert.Equal(t, int32(msgCount), received.Load())
+}
+
+func TestPartitioned(t *testing.T) {
+	type order struct {
+		customer string
+		seq      int
+	}
+
+	const ordersPerCustomer = 50
+
+	customers := []string{"alice", "bob", "carol", "dave"}
+
+	var (
+		mu       sync.Mutex
+		received = make(map[string][]int)
+	)
+
+	p := NewPartitioned(func(o order) string { return o.customer }, 3, 8, BufferBlock, Forever(func(o order) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[o.customer] = append(received[o.customer], o.seq)
+	}), nil)
+
+	for seq := range ordersPerCustomer {
+		for _, c := range customers {
+			assert.True(t, p.Receive(order{customer: c, seq: seq}))
+		}
+	}
+
+	p.Close()
+
+	for _, c := range customers {
+		require.Len(t, received[c], ordersPerCustomer)
+		for i, seq := range received[c] {
+			assert.Equal(t, i, seq, "expected orders of %s to be processed in order", c)
+		}
+	}
+}
+
+func TestPartitioned_Parallel(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan int, 2)
+
+	p := NewPartitioned(func(i int) int { return i }, 64, 1, BufferBlock, Forever(func(i int) {
+		started <- i
+		<-release
+	}), nil)
+	defer p.Close()
+
+	// Find two keys on different partitions.
+	second := 1
+	for p.partition(second) == p.partition(0) {
+		second++
+	}
+
+	assert.True(t, p.Receive(0))
+	assert.True(t, p.Receive(second))
+
+	timeout := testTimer(t, time.Second)
+
+	for range 2 {
+		select {
+		case <-started:
+		case <-timeout.C:
+			t.Fatalf("expected messages with different keys to be processed in parallel")
+		}
+	}
+
+	close(release)
+}
+
+func TestPartitioned_Unsubscribe(t *testing.T) {
+	p := NewPartitioned(func(i int) int { return i }, 4, 1, BufferBlock, func(i int) bool {
+		return i != 0
+	}, nil)
+
+	assert.True(t, p.Receive(0))
+	<-p.partitions[p.partition(0)].done // the worker quits once every partition is stopped
+
+	for i := 1; i < 10; i++ {
+		assert.False(t, p.Receive(i))
+	}
+
+	p.Close()
+}
+
+func TestPartitioned_Overflow(t *testing.T) {
+	release := make(chan struct{})
+	var dropped []int
+
+	p := NewPartitioned(func(int) int { return 0 }, 2, 2, BufferDropNewest, Forever(func(int) {
+		<-release // busy partition
+	}), func(i int) {
+		dropped = append(dropped, i)
+	})
+
+	for i := range 10 {
+		assert.True(t, p.Receive(i), "expected publishing not to block")
+	}
+
+	close(release)
+	p.Close()
+
+	// One message is being processed and the buffer holds two more, the rest is dropped.
+	assert.GreaterOrEqual(t, len(dropped), 10-3)
+}
+
+func TestRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	var calls int
+	s := Retry(func(i int) error {
+		calls++
+		if calls < 3 {
+			return errFailed
+		}
+		return nil
+	}, WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond))
+
+	assert.NoError(t, s(1))
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_GiveUp(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	var (
+		calls    int
+		gaveUp   any
+		attempts int
+	)
+
+	s := Retry(func(i int) error {
+		calls++
+		return errFailed
+	},
+		WithMaxAttempts(4),
+		WithBackoff(time.Millisecond, 2*time.Millisecond),
+		WithBackoffMultiplier(1.5),
+		WithJitter(0.5),
+		WithOnGiveUp(func(msg any, err error, n int) {
+			gaveUp = msg
+			attempts = n
+			assert.ErrorIs(t, err, errFailed)
+		}))
+
+	err := s(1234)
+
+	var retryErr *RetryError
+	require.ErrorAs(t, err, &retryErr)
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 4, retryErr.Attempts())
+
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, 1234, gaveUp)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestRetry_Context(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int
+	s := Retry(func(i int) error {
+		calls++
+		cancel() // shutting down while the message is failing
+		return errFailed
+	}, WithBackoff(time.Hour, time.Hour), WithRetryContext(ctx))
+
+	result := make(chan error)
+	go func() {
+		result <- s(1)
+	}()
+
+	timeout := testTimer(t, time.Second)
+
+	select {
+	case err := <-result:
+		var retryErr *RetryError
+		require.ErrorAs(t, err, &retryErr)
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, 1, retryErr.Attempts())
+	case <-timeout.C:
+		t.Fatalf("expected retrying to stop once the context is done")
+	}
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_Unsubscribe(t *testing.T) {
+	var calls int
+	s := Retry(func(i int) error {
+		calls++
+		return ErrUnsubscribe
+	}, WithBackoff(time.Millisecond, time.Millisecond))
+
+	assert.ErrorIs(t, s(1), ErrUnsubscribe)
+	assert.Equal(t, 1, calls, "expected unsubscribing not to be retried")
+}
+
+func TestWithJitter(t *testing.T) {
+	const d = 100 * time.Millisecond
+
+	assert.Equal(t, d, withJitter(d, 0))
+
+	for range 100 {
+		j := withJitter(d, 0.2)
+		assert.GreaterOrEqual(t, j, 80*time.Millisecond)
+		assert.LessOrEqual(t, j, 120*time.Millisecond)
+	}
+}